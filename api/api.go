@@ -6,9 +6,12 @@ import (
 
 	v1 "github.com/SaidovZohid/competition-project/api/v1"
 	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/apikey"
 	logging "github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/slug"
 	"github.com/SaidovZohid/competition-project/pkg/token"
 	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/worker"
 
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
@@ -17,11 +20,14 @@ import (
 )
 
 type RouterOptions struct {
-	Cfg        *config.Config
-	Storage    storage.StorageI
-	InMemory   storage.InMemoryStorageI
-	TokenMaker token.Maker
-	Logger     *logging.Logger
+	Cfg             *config.Config
+	Storage         storage.StorageI
+	InMemory        storage.InMemoryStorageI
+	TokenMaker      token.Maker
+	SlugGenerator   slug.Generator
+	Logger          *logging.Logger
+	MetadataFetcher *worker.MetadataFetcher
+	WebhookSender   *worker.WebhookSender
 }
 
 // @Security ApiKeyAuth
@@ -32,41 +38,120 @@ type RouterOptions struct {
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey XApiKey
+// @in header
+// @name X-API-Key
 func New(opt *RouterOptions) *gin.Engine {
 	router := gin.Default()
 
+	// client ips are taken from X-Forwarded-For only behind trusted
+	// proxies, otherwise clients could pick the ip they are limited by
+	err := router.SetTrustedProxies(opt.Cfg.TrustedProxies)
+	if err != nil {
+		opt.Logger.WithError(err).Fatal("invalid TRUSTED_PROXIES")
+	}
+
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowAllOrigins = true
 	corsConfig.AllowCredentials = true
 	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, "*")
+	corsConfig.ExposeHeaders = append(corsConfig.ExposeHeaders, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After")
 	router.Use(cors.New(corsConfig))
 
 	handlerV1 := v1.New(&v1.HandlerV1Options{
-		Cfg:        opt.Cfg,
-		Storage:    opt.Storage,
-		InMemory:   opt.InMemory,
-		TokenMaker: opt.TokenMaker,
-		Logger:     opt.Logger,
+		Cfg:             opt.Cfg,
+		Storage:         opt.Storage,
+		InMemory:        opt.InMemory,
+		TokenMaker:      opt.TokenMaker,
+		SlugGenerator:   opt.SlugGenerator,
+		Logger:          opt.Logger,
+		MetadataFetcher: opt.MetadataFetcher,
+		WebhookSender:   opt.WebhookSender,
 	})
 
+	limits := opt.Cfg.RateLimit
+	createLimit := handlerV1.RateLimit("create", limits.Create)
+	authLimit := handlerV1.RateLimit("auth", limits.Auth)
+	publicLimit := handlerV1.RateLimit("public", limits.Public)
+	redirectLimit := handlerV1.RateLimit("redirect", limits.Redirect)
+
+	// api keys reach only routes with a scope they have
+	linksRead := handlerV1.RequireScope(apikey.ScopeLinksRead)
+	linksWrite := handlerV1.RequireScope(apikey.ScopeLinksWrite)
+	statsRead := handlerV1.RequireScope(apikey.ScopeStatsRead)
+	sessionOnly := handlerV1.SessionOnly
+
 	apiV1 := router.Group("/v1")
-	apiV1.POST("/urls/make-short-url", handlerV1.AuthMiddleware, handlerV1.MakeShortUrl)
-	apiV1.GET("/urls/:shorturl", handlerV1.RedirectUrl)
+	authorized := apiV1.Group("", handlerV1.AuthFailureLimit(limits.AuthFailure), handlerV1.AuthMiddleware, handlerV1.RateLimit("api", limits.Api))
+	authorized.POST("/urls/make-short-url", linksWrite, createLimit, handlerV1.MakeShortUrl)
+	authorized.POST("/urls/bulk", linksWrite, createLimit, handlerV1.BulkCreateUrls)
+	authorized.GET("/urls", linksRead, handlerV1.GetAllUrls)
+	authorized.GET("/urls/trash", linksRead, handlerV1.GetTrash)
+	authorized.GET("/urls/broken", linksRead, handlerV1.GetBrokenUrls)
+	authorized.GET("/urls/export", linksRead, handlerV1.ExportUrls)
+	authorized.POST("/urls/import", linksWrite, createLimit, handlerV1.ImportUrls)
+	apiV1.GET("/urls/:id", redirectLimit, handlerV1.RedirectUrl)
+	apiV1.POST("/urls/:id", redirectLimit, handlerV1.UnlockUrl)
+	authorized.GET("/urls/:id/stats", statsRead, handlerV1.GetUrlStats)
+	authorized.GET("/urls/:id/qr", linksRead, handlerV1.GetQrCode)
+	authorized.POST("/urls/:id/rules", linksWrite, handlerV1.CreateUrlRule)
+	authorized.GET("/urls/:id/rules", linksRead, handlerV1.GetUrlRules)
+	authorized.PUT("/urls/:id/rules/:rule_id", linksWrite, handlerV1.UpdateUrlRule)
+	authorized.DELETE("/urls/:id/rules/:rule_id", linksWrite, handlerV1.DeleteUrlRule)
+	authorized.GET("/urls/:id/destinations", linksRead, handlerV1.GetDestinations)
+	authorized.PUT("/urls/:id/destinations", linksWrite, handlerV1.SetDestinations)
+	authorized.GET("/urls/:id/history", linksRead, handlerV1.GetUrlHistory)
+	authorized.POST("/urls/:id/revert/:revision", linksWrite, handlerV1.RevertUrl)
+	authorized.POST("/urls/:id/restore", linksWrite, handlerV1.RestoreUrl)
+
+	authorized.PUT("/urls/:id", linksWrite, handlerV1.UpdateUrl)
+	authorized.DELETE("/urls/:id", linksWrite, handlerV1.DeleteUrl)
 
-	apiV1.PUT("/urls/:id", handlerV1.AuthMiddleware, handlerV1.UpdateUrl)
-	apiV1.DELETE("/urls/:id", handlerV1.AuthMiddleware, handlerV1.DeleteUrl)
+	apiV1.GET("/users/:id", publicLimit, handlerV1.GetUser)
+	apiV1.GET("/users", publicLimit, handlerV1.GetAllUsers)
+	authorized.PUT("/users/password", sessionOnly, handlerV1.ChangePassword)
+	authorized.PUT("/users/:id", sessionOnly, handlerV1.UpdateUser)
+	authorized.DELETE("/users/:id", sessionOnly, handlerV1.DeleteUser)
+	apiV1.GET("/users/email/:email", publicLimit, handlerV1.GetUserByEmail)
+	apiV1.POST("/users/restore", authLimit, handlerV1.RestoreUser)
 
-	apiV1.GET("/users/:id", handlerV1.GetUser)
-	apiV1.GET("/users", handlerV1.GetAllUsers)
-	apiV1.PUT("/users/:id", handlerV1.AuthMiddleware, handlerV1.UpdateUser)
-	apiV1.DELETE("/users/:id", handlerV1.AuthMiddleware, handlerV1.DeleteUser)
-	apiV1.GET("/users/email/:email", handlerV1.GetUserByEmail)
+	authorized.POST("/domains", sessionOnly, handlerV1.CreateDomain)
+	authorized.POST("/domains/:id/verify", sessionOnly, handlerV1.VerifyDomain)
+	authorized.GET("/domains", linksRead, handlerV1.GetAllDomains)
+	authorized.DELETE("/domains/:id", sessionOnly, handlerV1.DeleteDomain)
 
-	apiV1.POST("/auth/register", handlerV1.Register)
-	apiV1.POST("/auth/verify", handlerV1.Verify)
-	apiV1.POST("/auth/login", handlerV1.Login)
+	authorized.POST("/tags", linksWrite, handlerV1.CreateTag)
+	authorized.GET("/tags", linksRead, handlerV1.GetAllTags)
+	authorized.PUT("/tags/:id", linksWrite, handlerV1.UpdateTag)
+	authorized.DELETE("/tags/:id", linksWrite, handlerV1.DeleteTag)
+
+	authorized.POST("/folders", linksWrite, handlerV1.CreateFolder)
+	authorized.GET("/folders", linksRead, handlerV1.GetAllFolders)
+	authorized.PUT("/folders/:id", linksWrite, handlerV1.UpdateFolder)
+	authorized.DELETE("/folders/:id", linksWrite, handlerV1.DeleteFolder)
+
+	authorized.POST("/api-keys", sessionOnly, handlerV1.CreateApiKey)
+	authorized.GET("/api-keys", sessionOnly, handlerV1.GetAllApiKeys)
+	authorized.DELETE("/api-keys/:id", sessionOnly, handlerV1.DeleteApiKey)
+
+	authorized.POST("/webhooks", sessionOnly, handlerV1.CreateWebhook)
+	authorized.GET("/webhooks", sessionOnly, handlerV1.GetAllWebhooks)
+	authorized.PUT("/webhooks/:id", sessionOnly, handlerV1.UpdateWebhook)
+	authorized.DELETE("/webhooks/:id", sessionOnly, handlerV1.DeleteWebhook)
+	authorized.GET("/webhooks/:id/deliveries", sessionOnly, handlerV1.GetWebhookDeliveries)
+	authorized.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", sessionOnly, handlerV1.RedeliverWebhook)
+
+	apiV1.POST("/auth/register", authLimit, handlerV1.Register)
+	apiV1.POST("/auth/verify", authLimit, handlerV1.Verify)
+	apiV1.POST("/auth/login", authLimit, handlerV1.Login)
+	apiV1.POST("/auth/refresh", authLimit, handlerV1.RefreshToken)
+	authorized.POST("/auth/logout", sessionOnly, handlerV1.Logout)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// short links of PUBLIC_BASE_URL and branded domains live on the root path
+	router.GET("/:shorturl", redirectLimit, handlerV1.RedirectUrl)
+	router.POST("/:shorturl", redirectLimit, handlerV1.UnlockUrl)
+
 	return router
 }
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	logging "github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore map[string]string
+
+func (m memoryStore) Set(key, value string, exp time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func (m memoryStore) Get(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func (m memoryStore) Del(keys ...string) error {
+	for _, key := range keys {
+		delete(m, key)
+	}
+	return nil
+}
+
+func (m memoryStore) Exists(key string) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+
+func (m memoryStore) Incr(key string, exp time.Duration) (int64, error) {
+	return m.IncrBy(key, 1, exp)
+}
+
+func (m memoryStore) IncrBy(key string, n int64, exp time.Duration) (int64, error) {
+	value, _ := strconv.ParseInt(m[key], 10, 64)
+	value += n
+	m[key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func TestRateLimitIgnoresForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	log := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	router := New(&RouterOptions{
+		Cfg: &config.Config{
+			IpHashSecret: "secret",
+			RateLimit: config.RateLimit{
+				Window: time.Minute,
+				Auth:   2,
+			},
+		},
+		InMemory: memoryStore{},
+		Logger:   &log,
+	})
+
+	login := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// the empty body is rejected before the user is looked up
+	require.Equal(t, http.StatusBadRequest, login("198.51.100.1"))
+	require.Equal(t, http.StatusBadRequest, login("198.51.100.2"))
+	require.Equal(t, http.StatusTooManyRequests, login("198.51.100.3"))
+}
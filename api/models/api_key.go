@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type ApiKey struct {
+	Id     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// Key is sent in the X-API-Key header, it is shown only once, when
+	// the key is created
+	Key        string     `json:"key,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateApiKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// ExpiresAt is optional, keys without it are valid until revoked
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type GetAllApiKeysResponse struct {
+	ApiKeys []*ApiKey `json:"api_keys"`
+	Count   int32     `json:"count"`
+}
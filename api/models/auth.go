@@ -16,6 +16,8 @@ type AuthResponse struct {
 	Email       string    `json:"email"`
 	CreatedAt   time.Time `json:"created_at"`
 	AccessToken string    `json:"access_token"`
+	// RefreshToken gets new tokens from /auth/refresh, it works once
+	RefreshToken string `json:"refresh_token"`
 }
 
 type AuthPayload struct {
@@ -37,6 +39,21 @@ type LoginRequest struct {
 }
 
 type LoginRes struct {
-	User        User   `json:"user"`
-	AccessToken string `json:"access_token"`
+	User         User   `json:"user"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokensResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6,max=16"`
 }
@@ -0,0 +1,39 @@
+package models
+
+type GetUrlStatsParams struct {
+	Interval string `json:"interval" default:"day" enums:"hour,day,week"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+type UrlStatsResponse struct {
+	UrlId          int64               `json:"url_id"`
+	Interval       string              `json:"interval"`
+	From           string              `json:"from"`
+	To             string              `json:"to"`
+	TotalClicks    int64               `json:"total_clicks"`
+	UniqueVisitors int64               `json:"unique_visitors"`
+	Series         []*ClickSeriesPoint `json:"series"`
+	Referrers      []*ClickBreakdown   `json:"referrers"`
+	Browsers       []*ClickBreakdown   `json:"browsers"`
+	Variants       []*VariantStats     `json:"variants"`
+}
+
+type ClickSeriesPoint struct {
+	Bucket string `json:"bucket"`
+	Clicks int64  `json:"clicks"`
+}
+
+type ClickBreakdown struct {
+	Name   string `json:"name"`
+	Clicks int64  `json:"clicks"`
+}
+
+// VariantStats compares the A/B destinations of the url
+type VariantStats struct {
+	DestinationId  int64  `json:"destination_id"`
+	TargetUrl      string `json:"target_url"`
+	Weight         int    `json:"weight"`
+	Clicks         int64  `json:"clicks"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
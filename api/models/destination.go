@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Destination struct {
+	Id        int64  `json:"id"`
+	TargetUrl string `json:"target_url"`
+	Weight    int    `json:"weight"`
+	// Share is the percent of visitors the destination gets
+	Share     float64   `json:"share"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DestinationRequest struct {
+	// Id of an existing destination to update, empty for a new one
+	Id        int64  `json:"id"`
+	TargetUrl string `json:"target_url" binding:"required"`
+	Weight    int    `json:"weight" binding:"required" example:"50"`
+}
+
+type SetDestinationsRequest struct {
+	// Sticky keeps visitors on the destination they got first
+	Sticky       bool                  `json:"sticky"`
+	Destinations []*DestinationRequest `json:"destinations"`
+}
+
+type DestinationsResponse struct {
+	Sticky       bool           `json:"sticky"`
+	Destinations []*Destination `json:"destinations"`
+	// FallbackUrl is used when the url has no destinations
+	FallbackUrl string `json:"fallback_url"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type Domain struct {
+	Id     int64  `json:"id"`
+	UserId int64  `json:"user_id"`
+	Host   string `json:"host"`
+	// VerificationRecord is the TXT record which has to hold
+	// VerificationToken before the domain can be verified
+	VerificationRecord string     `json:"verification_record"`
+	VerificationToken  string     `json:"verification_token"`
+	VerifiedAt         *time.Time `json:"verified_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+type CreateDomainRequest struct {
+	Host string `json:"host" binding:"required"`
+}
+
+type GetAllDomainsResponse struct {
+	Domains []*Domain `json:"domains"`
+	Count   int32     `json:"count"`
+}
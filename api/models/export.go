@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ExportUrl uses the field names of CreateShortUrlRequest, so an export
+// can be imported back as it is
+type ExportUrl struct {
+	Id             int64      `json:"id"`
+	OriginalUrl    string     `json:"original_url"`
+	CustomUrl      string     `json:"custom_url"`
+	ShortUrl       string     `json:"short_url"`
+	DomainId       int64      `json:"domain_id"`
+	FolderId       int64      `json:"folder_id"`
+	TagIds         []int64    `json:"tag_ids"`
+	MaxClicks      *int64     `json:"max_clicks"`
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Active         bool       `json:"active"`
+	InactiveReason string     `json:"inactive_reason"`
+	FallbackUrl    string     `json:"fallback_url"`
+	Preview        UrlPreview `json:"preview"`
+	Protected      bool       `json:"protected"`
+	Clicks         int64      `json:"clicks"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Folder struct {
+	Id        int64     `json:"id"`
+	UserId    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Urls      int64     `json:"urls"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type GetAllFoldersResponse struct {
+	Folders []*Folder `json:"folders"`
+	Count   int32     `json:"count"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type UrlHealth struct {
+	StatusCode          int        `json:"status_code"`
+	LatencyMs           int64      `json:"latency_ms"`
+	FinalUrl            string     `json:"final_url"`
+	Error               string     `json:"error"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	BrokenSince         *time.Time `json:"broken_since"`
+	CheckedAt           time.Time  `json:"checked_at"`
+}
+
+type BrokenUrl struct {
+	Url    *Url       `json:"url"`
+	Health *UrlHealth `json:"health"`
+}
+
+type GetBrokenUrlsResponse struct {
+	Urls  []*BrokenUrl `json:"urls"`
+	Count int32        `json:"count"`
+}
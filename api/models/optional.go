@@ -0,0 +1,27 @@
+package models
+
+import "encoding/json"
+
+// Optional tells a field which was left out of a request from one which
+// was set to null. Set is true for both null and a value, Value is nil
+// for null.
+type Optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+
+	return nil
+}
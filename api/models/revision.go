@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type UrlRevision struct {
+	Revision    int        `json:"revision"`
+	UserId      int64      `json:"user_id"`
+	OriginalUrl string     `json:"original_url"`
+	HashedUrl   string     `json:"hashed_url"`
+	MaxClicks   *int64     `json:"max_clicks"`
+	StartsAt    *time.Time `json:"starts_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	// Changes lists the fields which differ from the previous revision
+	Changes   []string  `json:"changes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UrlHistoryResponse struct {
+	Revisions []*UrlRevision `json:"revisions"`
+	Count     int32          `json:"count"`
+}
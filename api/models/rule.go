@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type UrlRule struct {
+	Id       int64  `json:"id"`
+	UrlId    int64  `json:"url_id"`
+	Position int    `json:"position"`
+	OS       string `json:"os,omitempty"`
+	Device   string `json:"device,omitempty"`
+	Language string `json:"language,omitempty"`
+	// TimeFrom and TimeTo are in HH:MM form
+	TimeFrom  string    `json:"time_from,omitempty"`
+	TimeTo    string    `json:"time_to,omitempty"`
+	Timezone  string    `json:"timezone,omitempty"`
+	TargetUrl string    `json:"target_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UrlRuleRequest conditions are optional, a rule matches when all of the
+// given ones match. The time window may wrap midnight, e.g. 22:00-06:00.
+type UrlRuleRequest struct {
+	Position  int    `json:"position"`
+	OS        string `json:"os" enums:"iOS,Android,Windows,macOS,ChromeOS,Linux,Other"`
+	Device    string `json:"device" enums:"mobile,tablet,desktop,bot"`
+	Language  string `json:"language" example:"en"`
+	TimeFrom  string `json:"time_from" example:"09:00"`
+	TimeTo    string `json:"time_to" example:"18:00"`
+	Timezone  string `json:"timezone" example:"Asia/Tashkent"`
+	TargetUrl string `json:"target_url" binding:"required"`
+}
+
+type GetUrlRulesResponse struct {
+	Rules []*UrlRule `json:"rules"`
+	Count int32      `json:"count"`
+	// FallbackUrl is used when no rule matches
+	FallbackUrl string `json:"fallback_url"`
+}
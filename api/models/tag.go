@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type Tag struct {
+	Id        int64     `json:"id"`
+	UserId    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Urls      int64     `json:"urls"`
+	Clicks    int64     `json:"clicks"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type GetAllTagsResponse struct {
+	Tags  []*Tag `json:"tags"`
+	Count int32  `json:"count"`
+}
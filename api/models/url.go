@@ -3,19 +3,63 @@ package models
 import "time"
 
 type Url struct {
-	Id          int64      `json:"id"`
-	UserId      int64      `json:"user_id"`
-	OriginalUrl string     `json:"original_url"`
-	HashedUrl   string     `json:"hashed_url"`
-	MaxClicks   *int64     `json:"max_clicks"`
-	ExpiresAt   *time.Time `json:"expires_at"`
-	CreatedAt   string     `json:"created_at"`
+	Id             int64      `json:"id"`
+	UserId         int64      `json:"user_id"`
+	OriginalUrl    string     `json:"original_url"`
+	HashedUrl      string     `json:"hashed_url"`
+	ShortUrl       string     `json:"short_url"`
+	DomainId       int64      `json:"domain_id"`
+	FolderId       int64      `json:"folder_id"`
+	TagIds         []int64    `json:"tag_ids"`
+	MaxClicks      *int64     `json:"max_clicks"`
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Active         bool       `json:"active"`
+	InactiveReason string     `json:"inactive_reason,omitempty"`
+	FallbackUrl    string     `json:"fallback_url"`
+	Preview        UrlPreview `json:"preview"`
+	// Metadata is taken from the destination page shortly after the url
+	// is created or its destination changes, it is null until then
+	Metadata  *UrlMetadata `json:"metadata"`
+	Clicks    int64        `json:"clicks"`
+	Protected bool         `json:"protected"`
+	DeletedAt *time.Time   `json:"deleted_at,omitempty"`
+	// RestorableUntil is set only for urls in the trash
+	RestorableUntil *time.Time `json:"restorable_until,omitempty"`
+	CreatedAt       string     `json:"created_at"`
 }
 type CreateShortUrlRequest struct {
 	OriginalUrl string `json:"original_url" binding:"required"`
 	MaxClicks   int64  `json:"max_clicks"`
 	Duration    string `json:"duration"`
 	CustomUrl   string `json:"custom_url"`
+	DomainId    int64  `json:"domain_id"`
+	Password    string `json:"password"`
+	// StartsAt is RFC3339, the link does not redirect before it
+	StartsAt string `json:"starts_at"`
+	// ExpiresAt is RFC3339, it is used instead of Duration when given
+	ExpiresAt string `json:"expires_at"`
+	// FallbackUrl is where visitors go once the link expired or ran out
+	// of clicks
+	FallbackUrl string  `json:"fallback_url"`
+	FolderId    int64   `json:"folder_id"`
+	TagIds      []int64 `json:"tag_ids"`
+	// Preview is shown to crawlers of chat apps and social networks
+	Preview UrlPreview `json:"preview"`
+}
+
+type UrlPreview struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+}
+
+type UrlMetadata struct {
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	CanonicalUrl string    `json:"canonical_url"`
+	FaviconUrl   string    `json:"favicon_url"`
+	FetchedAt    time.Time `json:"fetched_at"`
 }
 
 type CreateUrlRequest struct {
@@ -27,7 +71,63 @@ type CreateUrlRequest struct {
 }
 
 type UpdateUrlRequest struct {
-	HashedUrl string     `json:"hashed_url"`
-	MaxClicks *int64     `json:"max_clicks"`
-	ExpiresAt *time.Time `json:"expires_at"`
+	// OriginalUrl and HashedUrl are kept when empty
+	OriginalUrl string `json:"original_url"`
+	HashedUrl   string `json:"hashed_url"`
+	// MaxClicks, StartsAt and ExpiresAt are kept when omitted, null
+	// removes them. As on create, a MaxClicks of 0 means no limit.
+	MaxClicks Optional[int64]     `json:"max_clicks" swaggertype:"integer"`
+	StartsAt  Optional[time.Time] `json:"starts_at" swaggertype:"string" format:"date-time"`
+	ExpiresAt Optional[time.Time] `json:"expires_at" swaggertype:"string" format:"date-time"`
+	// Password and FallbackUrl are kept when omitted, an empty value
+	// removes them
+	Password    *string `json:"password"`
+	FallbackUrl *string `json:"fallback_url"`
+	// FolderId and TagIds are kept when omitted, 0 and an empty list
+	// take the url out of its folder and tags
+	FolderId *int64   `json:"folder_id"`
+	TagIds   *[]int64 `json:"tag_ids"`
+	// Preview is kept when omitted, an empty one removes it
+	Preview *UrlPreview `json:"preview"`
+}
+
+type GetQrCodeParams struct {
+	Format     string `json:"format" default:"png" enums:"png,svg"`
+	Size       int    `json:"size" default:"256"`
+	Level      string `json:"level" default:"medium" enums:"low,medium,high,highest"`
+	Foreground string `json:"fg" default:"#000000"`
+	Background string `json:"bg" default:"#ffffff"`
+	QuietZone  bool   `json:"quiet_zone" default:"true"`
+}
+
+type BulkCreateResult struct {
+	Row         int    `json:"row"`
+	OriginalUrl string `json:"original_url"`
+	Url         *Url   `json:"url,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type BulkCreateResponse struct {
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Results []*BulkCreateResult `json:"results"`
+}
+
+type GetAllUrlsParams struct {
+	Limit    int32  `json:"limit" binding:"required" default:"10"`
+	Page     int32  `json:"page" binding:"required" default:"1"`
+	Search   string `json:"search"`
+	Status   string `json:"status" enums:"active,scheduled,expired,exhausted"`
+	SortBy   string `json:"sort_by" default:"created_at" enums:"created_at,clicks,expires_at"`
+	Order    string `json:"order" default:"desc" enums:"asc,desc"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	TagId    int64  `json:"tag_id"`
+	FolderId int64  `json:"folder_id"`
+}
+
+type GetAllUrlsResponse struct {
+	Urls  []*Url `json:"urls"`
+	Count int32  `json:"count"`
 }
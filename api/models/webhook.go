@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	Id     int64    `json:"id"`
+	UserId int64    `json:"user_id"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+	// Secret signs the deliveries, it is shown only once, when the
+	// webhook is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	Url    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+}
+
+type UpdateWebhookRequest struct {
+	Url    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	// Active is kept when omitted, disabled webhooks get no deliveries
+	Active *bool `json:"active"`
+}
+
+type GetAllWebhooksResponse struct {
+	Webhooks []*Webhook `json:"webhooks"`
+	Count    int32      `json:"count"`
+}
+
+type WebhookDelivery struct {
+	Id            int64           `json:"id"`
+	WebhookId     int64           `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	StatusCode    int             `json:"status_code"`
+	Error         string          `json:"error"`
+	LastAttemptAt *time.Time      `json:"last_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Count      int32              `json:"count"`
+}
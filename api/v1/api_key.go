@@ -0,0 +1,198 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/apikey"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /api-keys [post]
+// @Summary Create an api key
+// @Description Create a long-lived key for scripts, it is sent in the X-API-Key header instead of an access token.
+// @Description Scopes are links:read, links:write and stats:read, account settings can not be changed with a key.
+// @Description The key is returned only by this request.
+// @Tags api-key
+// @Accept json
+// @Produce json
+// @Param data body models.CreateApiKeyRequest true "Data"
+// @Success 201 {object} models.ApiKey
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) CreateApiKey(ctx *gin.Context) {
+	var (
+		req models.CreateApiKeyRequest
+	)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to api key")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	name, err := validateName(req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scopes, err := validateScopes(req.Scopes)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrInvalidExpiresAt))
+		return
+	}
+
+	key, err := apikey.Generate()
+	if err != nil {
+		h.logger.WithError(err).Error("failed to generate api key")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	result, err := h.storage.ApiKey().Create(&repo.ApiKey{
+		UserId:    payload.UserID,
+		Name:      name,
+		Prefix:    apikey.Prefix(key),
+		Hash:      apikey.Hash(key),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to create api key")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := parseApiKeyModel(result)
+	response.Key = key
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /api-keys [get]
+// @Summary Get your api keys
+// @Description Get all api keys of the current user, only their prefixes are shown
+// @Tags api-key
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllApiKeysResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetAllApiKeys(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	keys, err := h.storage.ApiKey().GetAll(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get api keys")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetAllApiKeysResponse{
+		ApiKeys: make([]*models.ApiKey, 0),
+		Count:   int32(len(keys)),
+	}
+	for _, k := range keys {
+		response.ApiKeys = append(response.ApiKeys, parseApiKeyModel(k))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /api-keys/{id} [delete]
+// @Summary Revoke api key by id
+// @Description Revoke api key by id, requests made with it are rejected right away
+// @Tags api-key
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) DeleteApiKey(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	err = h.storage.ApiKey().Delete(int64(id), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to delete api key")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// validateScopes returns the scopes without duplicates
+func validateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !apikey.IsScope(s) {
+			return nil, ErrInvalidScope
+		}
+		if !hasScope(result, s) {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+func parseApiKeyModel(k *repo.ApiKey) *models.ApiKey {
+	return &models.ApiKey{
+		Id:         k.Id,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
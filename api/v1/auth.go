@@ -10,7 +10,6 @@ import (
 
 	"github.com/SaidovZohid/competition-project/api/models"
 	emailPkg "github.com/SaidovZohid/competition-project/pkg/email"
-	"github.com/SaidovZohid/competition-project/pkg/token"
 	"github.com/SaidovZohid/competition-project/pkg/utils"
 	"github.com/SaidovZohid/competition-project/storage/repo"
 	"github.com/gin-gonic/gin"
@@ -189,23 +188,20 @@ func (h *handlerV1) Verify(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	token, _, err := h.tokenMaker.CreateToken(&token.TokenParams{
-		UserID:   result.Id,
-		Email:    result.Email,
-		Duration: h.cfg.AccessTokenDuration,
-	})
+	tokens, err := h.startSession(result.Id, result.Email)
 	if err != nil {
 		h.logger.WithError(err).Error("failed to create token")
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		ID:          user.Id,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		AccessToken: token,
+		ID:           user.Id,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Email:        user.Email,
+		CreatedAt:    user.CreatedAt,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	})
 }
 
@@ -248,11 +244,7 @@ func (h *handlerV1) Login(c *gin.Context) {
 		c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
 		return
 	}
-	accessToken, _, err := h.tokenMaker.CreateToken(&token.TokenParams{
-		UserID:   user.Id,
-		Email:    user.Email,
-		Duration: h.cfg.AccessTokenDuration,
-	})
+	tokens, err := h.startSession(user.Id, user.Email)
 	if err != nil {
 		h.logger.WithError(err).Error("failed access create token")
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -267,6 +259,101 @@ func (h *handlerV1) Login(c *gin.Context) {
 			Email:     user.Email,
 			CreatedAt: user.CreatedAt,
 		},
-		AccessToken: accessToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// @Router /auth/refresh [post]
+// @Summary Refresh tokens
+// @Description Get a new access token and a new refresh token for a refresh token. Every refresh token works once,
+// @Description using one again ends its session, so a stolen token is useless once the user refreshed.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RefreshTokenRequest true "Data"
+// @Success 200 {object} models.TokensResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) RefreshToken(c *gin.Context) {
+	var (
+		req models.RefreshTokenRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tokens, err := h.refreshSession(req.RefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to refresh session")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/logout [post]
+// @Summary Logout
+// @Description End the session of the refresh token, the access token is revoked as well
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RefreshTokenRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) Logout(c *gin.Context) {
+	var (
+		req models.RefreshTokenRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	expiredAt, err := time.Parse(time.RFC3339, payload.ExpiredAt)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to parse token expiry")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	// the payload keeps whole seconds, the token may live a bit longer
+	err = h.revokeToken(payload.Id, expiredAt.Add(time.Second))
+	if err != nil {
+		h.logger.WithError(err).Error("failed to revoke token")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = h.logout(payload.UserID, req.RefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to end session")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
 	})
 }
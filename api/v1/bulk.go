@@ -0,0 +1,365 @@
+package v1
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/slug"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const BulkMaxRows = 1000
+
+// CreateRowsLimit counts the rows of bulk creation and import, a request
+// with many rows must not cost as much as one link
+const CreateRowsLimit = "create_rows"
+
+var (
+	ErrTooManyRows = errors.New("TOO_MANY_ROWS")
+	ErrInvalidCsv  = errors.New("INVALID_CSV")
+)
+
+func tooManyRows(max int) error {
+	return fmt.Errorf("%w: at most %d rows are allowed", ErrTooManyRows, max)
+}
+
+// bulkRow is one row of a bulk request, err is set when the row could not
+// even be parsed, e.g. max_clicks of a csv row is not a number
+type bulkRow struct {
+	req *models.CreateShortUrlRequest
+	err error
+	// exhausted and protected are read from export files, see
+	// checkExportedRows
+	exhausted bool
+	protected bool
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/bulk [post]
+// @Summary Make many short urls
+// @Description Make short urls from a json array or from a csv file with the header
+// @Description original_url,custom_url,max_clicks,duration,domain_id,password,starts_at,expires_at,fallback_url,folder_id,tag_ids,
+// @Description preview_title,preview_description,preview_image
+// @Description (only original_url is required, tag_ids are separated with ;).
+// @Description Urls are created in one transaction, failed rows are reported with their error code.
+// @Tags url
+// @Accept json
+// @Accept mpfd
+// @Produce json
+// @Produce text/csv
+// @Param data body []models.CreateShortUrlRequest false "Data"
+// @Param file formData file false "CSV file"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} models.BulkCreateResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+func (h *handlerV1) BulkCreateUrls(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	var rows []*bulkRow
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		rows, err = parseBulkCsv(ctx)
+	} else {
+		rows, err = parseBulkJson(ctx)
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("failed to parse bulk request")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if len(rows) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrBadRequest))
+		return
+	}
+	if len(rows) > BulkMaxRows {
+		ctx.JSON(http.StatusBadRequest, errorResponse(tooManyRows(BulkMaxRows)))
+		return
+	}
+	if !h.rateLimit(ctx, CreateRowsLimit, h.cfg.RateLimit.CreateRows, len(rows)) {
+		return
+	}
+
+	response, err := h.createBulk(rows, payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to create urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	if ctx.Query("format") == "csv" {
+		writeBulkCsv(ctx, response)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// createBulk validates every row and creates the valid ones in one
+// transaction. Rows with generated slugs that collided get new slugs
+// within it.
+func (h *handlerV1) createBulk(rows []*bulkRow, userID int64) (*models.BulkCreateResponse, error) {
+	response := models.BulkCreateResponse{
+		Total:   len(rows),
+		Results: make([]*models.BulkCreateResult, len(rows)),
+	}
+
+	var (
+		urls    = make([]*repo.Url, 0, len(rows))
+		indexes = make([]int, 0, len(rows))
+	)
+	for i, row := range rows {
+		response.Results[i] = &models.BulkCreateResult{
+			Row: i + 1,
+		}
+		if row.err != nil {
+			response.Results[i].Error = row.err.Error()
+			continue
+		}
+		response.Results[i].OriginalUrl = row.req.OriginalUrl
+
+		url, err := h.prepareUrl(row.req, userID)
+		if err != nil {
+			response.Results[i].Error = h.bulkRowError(err)
+			continue
+		}
+		urls = append(urls, url)
+		indexes = append(indexes, i)
+	}
+
+	// hosts are looked up before the urls are created, nothing may fail
+	// once they are committed
+	hosts, err := h.domainHosts(urls)
+	if err != nil {
+		return nil, err
+	}
+
+	rowErrors, err := h.storage.Url().CreateBulk(urls, func(u *repo.Url, attempt int) (string, error) {
+		if attempt >= slug.MaxAttempts {
+			return "", fmt.Errorf("failed to generate a free slug in %d attempts", slug.MaxAttempts)
+		}
+		return h.slugGenerator.Generate(u.OriginalUrl, attempt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range indexes {
+		url := urls[j]
+		switch err := rowErrors[j]; {
+		case err == nil:
+			shortUrl := slug.Url(h.cfg.PublicBaseUrl, hosts[url.DomainId], url.HashedUrl)
+			response.Results[i].Url = parseUrlModel(url, shortUrl)
+			h.cacheUrl(url)
+			h.fetchMetadata(url)
+			h.notify(webhook.EventUrlCreated, url)
+			response.Created++
+		case errors.Is(err, repo.ErrAlreadyExists):
+			response.Results[i].Error = ErrUrlUnavailable.Error()
+		default:
+			response.Results[i].Error = h.bulkRowError(err)
+		}
+	}
+	response.Failed = response.Total - response.Created
+
+	return &response, nil
+}
+
+// domainHosts returns hosts of the branded domains of the urls by id, the
+// default domain 0 has no host
+func (h *handlerV1) domainHosts(urls []*repo.Url) (map[int64]string, error) {
+	hosts := map[int64]string{0: ""}
+	for _, url := range urls {
+		if _, ok := hosts[url.DomainId]; ok {
+			continue
+		}
+
+		domain, err := h.storage.Domain().Get(url.DomainId)
+		if err != nil {
+			return nil, err
+		}
+		hosts[url.DomainId] = domain.Host
+	}
+
+	return hosts, nil
+}
+
+// bulkRowError hides internal errors behind INTERNAL_SERVER_ERROR
+func (h *handlerV1) bulkRowError(err error) string {
+	if prepareUrlStatus(err) == http.StatusInternalServerError {
+		h.logger.WithError(err).Error("failed to create bulk row")
+		return ErrInternalServer.Error()
+	}
+	return err.Error()
+}
+
+func parseBulkJson(ctx *gin.Context) ([]*bulkRow, error) {
+	var reqs []*models.CreateShortUrlRequest
+
+	err := ctx.ShouldBindJSON(&reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	return bulkRows(reqs), nil
+}
+
+func bulkRows(reqs []*models.CreateShortUrlRequest) []*bulkRow {
+	rows := make([]*bulkRow, 0, len(reqs))
+	for _, req := range reqs {
+		if req == nil {
+			rows = append(rows, &bulkRow{err: ErrBadRequest})
+			continue
+		}
+		rows = append(rows, &bulkRow{req: req})
+	}
+
+	return rows
+}
+
+func parseBulkCsv(ctx *gin.Context) ([]*bulkRow, error) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readCsvRows(file, BulkMaxRows, nil)
+}
+
+// readCsvRows reads rows of a csv with a header, aliases rename header
+// columns to the names of the bulk csv. When several columns get the same
+// name the first non empty value is used.
+func readCsvRows(r io.Reader, maxRows int, aliases map[string]string) ([]*bulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidCsv
+	}
+	columns := make(map[string][]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+		columns[name] = append(columns[name], i)
+	}
+	if _, ok := columns["original_url"]; !ok {
+		return nil, fmt.Errorf("%w: original_url column is required", ErrInvalidCsv)
+	}
+
+	rows := make([]*bulkRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCsv, err.Error())
+		}
+		if len(rows) == maxRows {
+			return nil, tooManyRows(maxRows)
+		}
+
+		column := func(name string) string {
+			for _, i := range columns[name] {
+				if i < len(record) && strings.TrimSpace(record[i]) != "" {
+					return strings.TrimSpace(record[i])
+				}
+			}
+			return ""
+		}
+
+		row := bulkRow{
+			req: &models.CreateShortUrlRequest{
+				OriginalUrl: column("original_url"),
+				CustomUrl:   column("custom_url"),
+				Duration:    column("duration"),
+				Password:    column("password"),
+				StartsAt:    column("starts_at"),
+				ExpiresAt:   column("expires_at"),
+				FallbackUrl: column("fallback_url"),
+				Preview: models.UrlPreview{
+					Title:       column("preview_title"),
+					Description: column("preview_description"),
+					Image:       column("preview_image"),
+				},
+			},
+		}
+		// slugs of short links are kept when there is no custom_url
+		if row.req.CustomUrl == "" {
+			row.req.CustomUrl = linkSlug(column("short_url"))
+		}
+		if v := column("max_clicks"); v != "" {
+			row.req.MaxClicks, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				row.err = ErrInvalidMaxClicks
+			}
+			row.exhausted = err == nil && row.req.MaxClicks == 0
+		}
+		if v := column("protected"); v != "" {
+			row.protected, _ = strconv.ParseBool(v)
+		}
+		if v := column("domain_id"); v != "" {
+			row.req.DomainId, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				row.err = ErrInvalidDomain
+			}
+		}
+		if v := column("folder_id"); v != "" {
+			row.req.FolderId, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				row.err = ErrInvalidFolder
+			}
+		}
+		if v := column("tag_ids"); v != "" {
+			row.req.TagIds, err = parseIDList(strings.ReplaceAll(v, ";", ","))
+			if err != nil {
+				row.err = ErrInvalidTag
+			}
+		}
+		rows = append(rows, &row)
+	}
+
+	return rows, nil
+}
+
+func writeBulkCsv(ctx *gin.Context, response *models.BulkCreateResponse) {
+	ctx.Header("Content-Disposition", `attachment; filename="urls.csv"`)
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Header().Set("Content-Type", "text/csv")
+
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Write([]string{"row", "original_url", "id", "short_url", "error"})
+	for _, r := range response.Results {
+		var id, shortUrl string
+		if r.Url != nil {
+			id = strconv.FormatInt(r.Url.Id, 10)
+			shortUrl = r.Url.ShortUrl
+		}
+		writer.Write([]string{strconv.Itoa(r.Row), r.OriginalUrl, id, shortUrl, r.Error})
+	}
+	writer.Flush()
+}
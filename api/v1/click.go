@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/useragent"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/stats [get]
+// @Summary Get url click statistics
+// @Description Get total clicks, time series, referrer/browser breakdowns and A/B variant clicks of your url
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param filter query models.GetUrlStatsParams false "Filter"
+// @Success 200 {object} models.UrlStatsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) GetUrlStats(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	params, err := validateStatsParams(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to validate stats params")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	url, err := h.getOwnUrl(int64(id), payload.UserID)
+	if err != nil {
+		h.ownUrlError(ctx, err)
+		return
+	}
+
+	params.UrlID = url.Id
+	stats, err := h.storage.Click().GetStats(params)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get url stats")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	destinations, err := h.storage.Destination().GetAll(url.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get destinations")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, getUrlStatsResponse(params, stats, destinations))
+}
+
+// recordClick stores the click in the background, so that a slow
+// analytics insert never delays the redirect itself. Webhooks are told
+// once the clicks of the url reach a new threshold.
+func (h *handlerV1) recordClick(ctx *gin.Context, url *repo.Url, destinationID int64) {
+	click := repo.Click{
+		UrlId:          url.Id,
+		Referrer:       ctx.Request.Referer(),
+		UserAgent:      ctx.Request.UserAgent(),
+		IpHash:         h.hashIP(ctx.ClientIP()),
+		AcceptLanguage: ctx.GetHeader("Accept-Language"),
+		DestinationId:  destinationID,
+	}
+
+	go func() {
+		created, err := h.storage.Click().Create(&click)
+		if err != nil {
+			h.logger.WithError(err).Error("failed to record click")
+			return
+		}
+
+		err = h.webhookSender.NotifyClicks(url, created.UrlClicks)
+		if err != nil {
+			h.logger.WithError(err).WithField("url_id", url.Id).Error("failed to notify webhooks")
+		}
+	}()
+}
+
+// hashIP keys the client ip with the ip hash secret, so raw addresses are
+// never stored but unique visitors can still be counted. Rate limits and
+// unlock attempts are counted by it as well.
+func (h *handlerV1) hashIP(ip string) string {
+	mac := hmac.New(sha256.New, []byte(h.cfg.IpHashSecret))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func getUrlStatsResponse(params *repo.GetClickStatsParams, data *repo.ClickStats, destinations []*repo.Destination) *models.UrlStatsResponse {
+	response := models.UrlStatsResponse{
+		UrlId:          params.UrlID,
+		Interval:       params.Interval,
+		From:           params.From.Format(time.RFC3339),
+		To:             params.To.Format(time.RFC3339),
+		TotalClicks:    data.TotalClicks,
+		UniqueVisitors: data.UniqueVisitors,
+		Series:         make([]*models.ClickSeriesPoint, 0),
+		Referrers:      make([]*models.ClickBreakdown, 0),
+		Browsers:       make([]*models.ClickBreakdown, 0),
+		Variants:       make([]*models.VariantStats, 0),
+	}
+
+	for _, p := range data.Series {
+		response.Series = append(response.Series, &models.ClickSeriesPoint{
+			Bucket: p.Bucket.Format(time.RFC3339),
+			Clicks: p.Clicks,
+		})
+	}
+
+	for _, r := range data.Referrers {
+		name := r.Value
+		if name == "" {
+			name = "direct"
+		}
+		response.Referrers = append(response.Referrers, &models.ClickBreakdown{
+			Name:   name,
+			Clicks: r.Clicks,
+		})
+	}
+
+	browsers := make(map[string]int64)
+	for _, ua := range data.UserAgents {
+		browsers[useragent.Browser(ua.Value)] += ua.Clicks
+	}
+	for name, clicks := range browsers {
+		response.Browsers = append(response.Browsers, &models.ClickBreakdown{
+			Name:   name,
+			Clicks: clicks,
+		})
+	}
+	sort.Slice(response.Browsers, func(i, j int) bool {
+		if response.Browsers[i].Clicks == response.Browsers[j].Clicks {
+			return response.Browsers[i].Name < response.Browsers[j].Name
+		}
+		return response.Browsers[i].Clicks > response.Browsers[j].Clicks
+	})
+
+	// destinations without clicks are listed too, deleted ones are not
+	clicks := make(map[int64]*repo.DestinationClicks)
+	for _, d := range data.Destinations {
+		clicks[d.DestinationId] = d
+	}
+	for _, d := range destinations {
+		v := models.VariantStats{
+			DestinationId: d.Id,
+			TargetUrl:     d.TargetUrl,
+			Weight:        d.Weight,
+		}
+		if c, ok := clicks[d.Id]; ok {
+			v.Clicks = c.Clicks
+			v.UniqueVisitors = c.UniqueVisitors
+		}
+		response.Variants = append(response.Variants, &v)
+	}
+
+	return &response
+}
@@ -0,0 +1,250 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DestinationsCacheKey = "url_destinations_"
+	VariantCookiePrefix  = "url_variant_"
+	VariantCookieTTL     = 30 * 24 * time.Hour
+
+	DestinationsMaxCount = 20
+	DestinationMaxWeight = 10000
+)
+
+var (
+	ErrInvalidWeight      = fmt.Errorf("INVALID_WEIGHT: weight must be between 1 and %d", DestinationMaxWeight)
+	ErrTooManyVariants    = fmt.Errorf("TOO_MANY_DESTINATIONS: at most %d destinations are allowed", DestinationsMaxCount)
+	ErrUnknownDestination = errors.New("UNKNOWN_DESTINATION")
+)
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/destinations [get]
+// @Summary Get A/B destinations
+// @Description Get destinations the traffic of the url is split across
+// @Tags destination
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.DestinationsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) GetDestinations(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	destinations, err := h.storage.Destination().GetAll(url.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get destinations")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, getDestinationsResponse(url, url.StickyDestinations, destinations))
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/destinations [put]
+// @Summary Set A/B destinations
+// @Description Replace destinations of the url, e.g. weights 70 and 30 send 70% of visitors to the first one.
+// @Description Destinations sent with an id are updated, the ones left out are deleted. An empty list turns the split off.
+// @Tags destination
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.SetDestinationsRequest true "Data"
+// @Success 200 {object} models.DestinationsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) SetDestinations(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.SetDestinationsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to destinations")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	destinations, err := prepareDestinations(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	destinations, err = h.storage.Destination().Set(url.Id, req.Sticky, destinations)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(ErrUnknownDestination))
+			return
+		}
+		h.logger.WithError(err).Error("failed to set destinations")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	// the sticky flag lives on the cached url
+	h.invalidateUrl(url)
+	err = h.inMemory.Del(DestinationsCacheKey + strconv.FormatInt(url.Id, 10))
+	if err != nil {
+		h.logger.WithError(err).Error("failed to delete destinations from redis db")
+	}
+
+	ctx.JSON(http.StatusOK, getDestinationsResponse(url, req.Sticky, destinations))
+}
+
+// pickDestination returns the variant the visitor keeps from an earlier
+// visit when the url is sticky, or a new one chosen by weight
+func (h *handlerV1) pickDestination(ctx *gin.Context, url *repo.Url, destinations []*repo.Destination) *repo.Destination {
+	cookie := VariantCookiePrefix + strconv.FormatInt(url.Id, 10)
+
+	if url.StickyDestinations {
+		if v, err := ctx.Cookie(cookie); err == nil {
+			for _, d := range destinations {
+				if strconv.FormatInt(d.Id, 10) == v {
+					return d
+				}
+			}
+		}
+	}
+
+	d := pickByWeight(destinations, rand.Intn)
+
+	if url.StickyDestinations {
+		ctx.SetSameSite(http.SameSiteLaxMode)
+		ctx.SetCookie(
+			cookie,
+			strconv.FormatInt(d.Id, 10),
+			int(VariantCookieTTL.Seconds()),
+			"/",
+			"",
+			ctx.Request.TLS != nil,
+			true,
+		)
+	}
+
+	return d
+}
+
+// pickByWeight takes intn as a parameter, so the choice can be made
+// deterministic
+func pickByWeight(destinations []*repo.Destination, intn func(n int) int) *repo.Destination {
+	total := 0
+	for _, d := range destinations {
+		total += d.Weight
+	}
+
+	n := intn(total)
+	for _, d := range destinations {
+		if n < d.Weight {
+			return d
+		}
+		n -= d.Weight
+	}
+
+	return destinations[len(destinations)-1]
+}
+
+func (h *handlerV1) getDestinations(urlID int64) ([]*repo.Destination, error) {
+	key := DestinationsCacheKey + strconv.FormatInt(urlID, 10)
+
+	data, err := h.inMemory.Get(key)
+	if err == nil {
+		var destinations []*repo.Destination
+		if err := json.Unmarshal([]byte(data), &destinations); err == nil {
+			return destinations, nil
+		}
+		h.logger.WithError(err).Error("failed to unmarshal cached destinations")
+	}
+
+	destinations, err := h.storage.Destination().GetAll(urlID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(destinations)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to marshal destinations")
+		return destinations, nil
+	}
+	err = h.inMemory.Set(key, string(b), UrlCacheTTL)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to set destinations to redis db")
+	}
+
+	return destinations, nil
+}
+
+func prepareDestinations(req *models.SetDestinationsRequest) ([]*repo.Destination, error) {
+	if len(req.Destinations) > DestinationsMaxCount {
+		return nil, ErrTooManyVariants
+	}
+
+	destinations := make([]*repo.Destination, 0, len(req.Destinations))
+	for _, d := range req.Destinations {
+		if d == nil {
+			return nil, ErrBadRequest
+		}
+
+		if !isHttpUrl(d.TargetUrl) {
+			return nil, ErrInvalidUrl
+		}
+
+		if d.Weight < 1 || d.Weight > DestinationMaxWeight {
+			return nil, ErrInvalidWeight
+		}
+
+		destinations = append(destinations, &repo.Destination{
+			Id:        d.Id,
+			TargetUrl: d.TargetUrl,
+			Weight:    d.Weight,
+		})
+	}
+
+	return destinations, nil
+}
+
+func getDestinationsResponse(url *repo.Url, sticky bool, destinations []*repo.Destination) *models.DestinationsResponse {
+	response := models.DestinationsResponse{
+		Sticky:       sticky,
+		Destinations: make([]*models.Destination, 0),
+		FallbackUrl:  url.OriginalUrl,
+	}
+
+	total := 0
+	for _, d := range destinations {
+		total += d.Weight
+	}
+	for _, d := range destinations {
+		response.Destinations = append(response.Destinations, &models.Destination{
+			Id:        d.Id,
+			TargetUrl: d.TargetUrl,
+			Weight:    d.Weight,
+			Share:     float64(d.Weight) * 100 / float64(total),
+			CreatedAt: d.CreatedAt,
+		})
+	}
+
+	return &response
+}
@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickByWeight(t *testing.T) {
+	destinations := []*repo.Destination{
+		{Id: 1, Weight: 50},
+		{Id: 2, Weight: 30},
+		{Id: 3, Weight: 20},
+	}
+
+	tests := []struct {
+		n    int
+		want int64
+	}{
+		{0, 1},
+		{49, 1},
+		{50, 2},
+		{79, 2},
+		{80, 3},
+		{99, 3},
+	}
+
+	for _, tt := range tests {
+		d := pickByWeight(destinations, func(total int) int {
+			require.Equal(t, 100, total)
+			return tt.n
+		})
+		require.Equal(t, tt.want, d.Id, tt.n)
+	}
+}
+
+func TestPickDestinationSticky(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &handlerV1{}
+
+	// a variant without weight is picked only from the cookie
+	destinations := []*repo.Destination{
+		{Id: 1, Weight: 0},
+		{Id: 2, Weight: 1},
+	}
+
+	pick := func(url *repo.Url, cookie *http.Cookie) (*repo.Destination, []*http.Cookie) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/abc", nil)
+		if cookie != nil {
+			ctx.Request.AddCookie(cookie)
+		}
+		return h.pickDestination(ctx, url, destinations), w.Result().Cookies()
+	}
+
+	// sticky urls keep the variant of the cookie
+	url := &repo.Url{Id: 7, StickyDestinations: true}
+	d, cookies := pick(url, &http.Cookie{Name: "url_variant_7", Value: "1"})
+	require.Equal(t, int64(1), d.Id)
+
+	// and give a cookie with the variant picked by weight
+	d, cookies = pick(url, nil)
+	require.Len(t, cookies, 1)
+	require.Equal(t, "url_variant_7", cookies[0].Name)
+	require.Equal(t, "2", cookies[0].Value)
+	require.Equal(t, int64(2), d.Id)
+
+	// cookies of variants which were removed are ignored
+	d, _ = pick(url, &http.Cookie{Name: "url_variant_7", Value: "3"})
+	require.Equal(t, int64(2), d.Id)
+
+	// other urls ignore the cookie and give none
+	url = &repo.Url{Id: 7}
+	d, cookies = pick(url, &http.Cookie{Name: "url_variant_7", Value: "1"})
+	require.Equal(t, int64(2), d.Id)
+	require.Empty(t, cookies)
+}
+
+func TestRedirectDestinations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	strg := newFakeStorage()
+	h := newTestHandler(strg, newFakeInMemory())
+
+	router := gin.New()
+	router.GET("/:shorturl", h.RedirectUrl)
+
+	strg.urls.urls["abc"] = &repo.Url{Id: 1, UserId: 1, OriginalUrl: "https://example.com", HashedUrl: "abc", Active: true, StickyDestinations: true}
+	strg.destinations[1] = []*repo.Destination{
+		{Id: 1, UrlId: 1, TargetUrl: "https://example.com/a", Weight: 0},
+		{Id: 2, UrlId: 1, TargetUrl: "https://example.com/b", Weight: 1},
+	}
+
+	get := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "https://example.com/b", w.Header().Get("Location"))
+	click := <-strg.clicks.created
+	require.Equal(t, int64(2), click.DestinationId)
+
+	// the variant of the cookie is kept and counted
+	w = get(&http.Cookie{Name: "url_variant_1", Value: "1"})
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "https://example.com/a", w.Header().Get("Location"))
+	click = <-strg.clicks.created
+	require.Equal(t, int64(1), click.DestinationId)
+}
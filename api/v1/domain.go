@@ -0,0 +1,307 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DomainVerificationPrefix makes the name of the TXT record a domain
+	// is verified with
+	DomainVerificationPrefix = "_shortener-verification."
+	DomainLookupTimeout      = 5 * time.Second
+)
+
+// @Security ApiKeyAuth
+// @Router /domains [post]
+// @Summary Register a branded domain
+// @Description Register a branded domain, its DNS record has to point to this service. The domain is
+// @Description used only after it is verified, see POST /domains/{id}/verify.
+// @Tags domain
+// @Accept json
+// @Produce json
+// @Param data body models.CreateDomainRequest true "Data"
+// @Success 201 {object} models.Domain
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) CreateDomain(ctx *gin.Context) {
+	var (
+		req models.CreateDomainRequest
+	)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to domain")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	host := normalizeHost(req.Host)
+	if !strings.Contains(host, ".") || host == normalizeHost(h.cfg.PublicBaseUrl) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrInvalidDomain))
+		return
+	}
+
+	res, _ := h.storage.Domain().GetByHost(host)
+	if res != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrDomainExists))
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		h.logger.WithError(err).Error("failed to generate verification token")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	domain, err := h.storage.Domain().Create(&repo.Domain{
+		UserId:            payload.UserID,
+		Host:              host,
+		VerificationToken: hex.EncodeToString(b),
+	})
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrDomainExists))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to create domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, parseDomainModel(domain))
+}
+
+// @Security ApiKeyAuth
+// @Router /domains/{id}/verify [post]
+// @Summary Verify domain by id
+// @Description Verify the domain is yours, its verification_record has to be a TXT record with the
+// @Description verification_token. Hosts can be claimed by several users, the first who verifies it gets it.
+// @Tags domain
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Domain
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) VerifyDomain(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	domain, err := h.storage.Domain().Get(int64(id))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.logger.WithError(err).Error("failed to get domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	if domain == nil || domain.UserId != payload.UserID {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		return
+	}
+	if domain.VerifiedAt != nil {
+		ctx.JSON(http.StatusOK, parseDomainModel(domain))
+		return
+	}
+
+	if !h.hasVerificationRecord(ctx, domain) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrDomainNotVerified))
+		return
+	}
+
+	domain, err = h.storage.Domain().Verify(domain.Id)
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrDomainExists))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to verify domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	// the host may be cached as an unknown one by the redirect path
+	err = h.inMemory.Del(DomainCacheKey + domain.Host)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to delete domain from redis db")
+	}
+
+	ctx.JSON(http.StatusOK, parseDomainModel(domain))
+}
+
+// hasVerificationRecord reports whether the TXT records of the domain hold
+// its verification token
+func (h *handlerV1) hasVerificationRecord(ctx *gin.Context, domain *repo.Domain) bool {
+	lookupCtx, cancel := context.WithTimeout(ctx.Request.Context(), DomainLookupTimeout)
+	defer cancel()
+
+	records, err := h.lookupTXT(lookupCtx, DomainVerificationPrefix+domain.Host)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		h.logger.WithError(err).Error("failed to look up verification record")
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == domain.VerificationToken {
+			return true
+		}
+	}
+	return false
+}
+
+// @Security ApiKeyAuth
+// @Router /domains [get]
+// @Summary Get your domains
+// @Description Get all branded domains of the current user
+// @Tags domain
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllDomainsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetAllDomains(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	domains, err := h.storage.Domain().GetAll(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get domains")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetAllDomainsResponse{
+		Domains: make([]*models.Domain, 0),
+		Count:   int32(len(domains)),
+	}
+	for _, d := range domains {
+		response.Domains = append(response.Domains, parseDomainModel(d))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /domains/{id} [delete]
+// @Summary Delete domain by id
+// @Description Delete domain by id. It is refused while links use the domain, also links in the trash,
+// @Description so links are never lost together with their domain.
+// @Tags domain
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+func (h *handlerV1) DeleteDomain(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	domain, err := h.storage.Domain().Get(int64(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to get domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = h.storage.Domain().Delete(domain.Id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		if errors.Is(err, repo.ErrInUse) {
+			ctx.JSON(http.StatusConflict, errorResponse(ErrDomainInUse))
+			return
+		}
+		h.logger.WithError(err).Error("failed to delete domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	// cached links of the domain become unreachable once the host is forgotten
+	err = h.inMemory.Del(DomainCacheKey + domain.Host)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to delete domain from redis db")
+	}
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// normalizeHost turns values like "https://Go.Example.com:443/path" into
+// "go.example.com", which is the form domains are stored in
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
+
+func parseDomainModel(domain *repo.Domain) *models.Domain {
+	return &models.Domain{
+		Id:                 domain.Id,
+		UserId:             domain.UserId,
+		Host:               domain.Host,
+		VerificationRecord: DomainVerificationPrefix + domain.Host,
+		VerificationToken:  domain.VerificationToken,
+		VerifiedAt:         domain.VerifiedAt,
+		CreatedAt:          domain.CreatedAt,
+	}
+}
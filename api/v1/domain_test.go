@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyDomain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	strg := newFakeStorage()
+	h := newTestHandler(strg, newFakeInMemory())
+
+	records := map[string][]string{}
+	h.lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if values, ok := records[name]; ok {
+			return values, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	router := gin.New()
+	router.GET("/:shorturl", h.RedirectUrl)
+	authorized := router.Group("", func(ctx *gin.Context) {
+		ctx.Set(h.cfg.AuthPayloadKey, Payload{UserID: 1})
+	})
+	authorized.POST("/domains/:id/verify", h.VerifyDomain)
+
+	strg.domains[5] = &repo.Domain{Id: 5, UserId: 1, Host: "go.example.com", VerificationToken: "token"}
+	strg.domains[6] = &repo.Domain{Id: 6, UserId: 2, Host: "go.example.com", VerificationToken: "other"}
+	strg.urls.urls["abc"] = &repo.Url{Id: 1, UserId: 1, OriginalUrl: "https://example.com", HashedUrl: "abc", DomainId: 5, Active: true}
+
+	redirect := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.Host = "go.example.com"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	verify := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/domains/"+id+"/verify", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// unverified domains are not resolved
+	require.Equal(t, http.StatusNotFound, redirect())
+
+	// without the record the domain stays unverified
+	require.Equal(t, http.StatusBadRequest, verify("5").Code)
+	records["_shortener-verification.go.example.com"] = []string{"other"}
+	require.Equal(t, http.StatusBadRequest, verify("5").Code)
+	require.Nil(t, strg.domains[5].VerifiedAt)
+
+	// domains of other users can not be verified
+	require.Equal(t, http.StatusNotFound, verify("6").Code)
+
+	records["_shortener-verification.go.example.com"] = []string{"other", "token"}
+	w := verify("5")
+	require.Equal(t, http.StatusOK, w.Code)
+	var domain models.Domain
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &domain))
+	require.NotNil(t, domain.VerifiedAt)
+	require.Equal(t, "_shortener-verification.go.example.com", domain.VerificationRecord)
+
+	// the host cached as unknown is forgotten by verifying
+	require.Equal(t, http.StatusFound, redirect())
+	<-strg.clicks.created
+}
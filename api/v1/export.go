@@ -0,0 +1,388 @@
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ExportFormatCsv    = "csv"
+	ExportFormatJson   = "json"
+	ExportFormatNdjson = "ndjson"
+	// ImportFormatBitly is a csv exported from Bitly
+	ImportFormatBitly = "bitly"
+
+	ImportMaxRows = 10000
+)
+
+var (
+	ErrInvalidFormat    = errors.New("INVALID_FORMAT")
+	ErrClicksExhausted  = errors.New("CLICKS_EXHAUSTED")
+	ErrPasswordRequired = errors.New("PASSWORD_REQUIRED")
+)
+
+// importUrl is a json row of an import, fields of exports which can not
+// be created as they are, are read to refuse the row
+type importUrl struct {
+	models.CreateShortUrlRequest
+	MaxClicks *int64 `json:"max_clicks"`
+	Protected bool   `json:"protected"`
+}
+
+var exportContentTypes = map[string]string{
+	ExportFormatCsv:    "text/csv",
+	ExportFormatJson:   "application/json",
+	ExportFormatNdjson: "application/x-ndjson",
+}
+
+var exportCsvHeader = []string{
+	"id", "original_url", "custom_url", "short_url", "domain_id", "folder_id", "tag_ids",
+	"max_clicks", "starts_at", "expires_at", "active", "inactive_reason", "fallback_url",
+	"preview_title", "preview_description", "preview_image", "protected", "clicks", "created_at",
+}
+
+// bitlyColumns renames columns of Bitly exports to the bulk csv ones
+var bitlyColumns = map[string]string{
+	"long_url":        "original_url",
+	"long url":        "original_url",
+	"destination":     "original_url",
+	"destination url": "original_url",
+	"custom_link":     "custom_url",
+	"custom link":     "custom_url",
+	"custom_links":    "custom_url",
+	"custom links":    "custom_url",
+	"link":            "short_url",
+	"bitlink":         "short_url",
+	"bitly link":      "short_url",
+	"short link":      "short_url",
+	"short_link":      "short_url",
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/export [get]
+// @Summary Export your urls
+// @Description Stream all urls of the current user as csv, a json array or newline delimited json
+// @Tags url
+// @Produce text/csv
+// @Produce json
+// @Produce application/x-ndjson
+// @Param format query string false "Format" Enums(csv, json, ndjson)
+// @Success 200 {array} models.ExportUrl
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) ExportUrls(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", ExportFormatCsv)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrInvalidFormat))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="urls.`+format+`"`)
+	ctx.Header("Content-Type", contentType)
+	ctx.Status(http.StatusOK)
+
+	var (
+		csvWriter = csv.NewWriter(ctx.Writer)
+		encoder   = json.NewEncoder(ctx.Writer)
+		// short url prefixes of domains, so a domain is read once
+		prefixes = make(map[int64]string)
+		count    int
+	)
+	switch format {
+	case ExportFormatCsv:
+		csvWriter.Write(exportCsvHeader)
+	case ExportFormatJson:
+		ctx.Writer.WriteString("[")
+	}
+
+	err = h.storage.Url().Export(payload.UserID, func(u *repo.Url) error {
+		prefix, ok := prefixes[u.DomainId]
+		if !ok {
+			shortUrl, err := h.shortUrl(&repo.Url{DomainId: u.DomainId})
+			if err != nil {
+				return err
+			}
+			prefix = shortUrl
+			prefixes[u.DomainId] = prefix
+		}
+		item := parseExportModel(u, prefix+u.HashedUrl)
+
+		switch format {
+		case ExportFormatCsv:
+			return csvWriter.Write(exportCsvRecord(item))
+		case ExportFormatJson:
+			if count > 0 {
+				ctx.Writer.WriteString(",")
+			}
+		}
+		count++
+		return encoder.Encode(item)
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to export urls")
+		if !ctx.Writer.Written() {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		}
+		return
+	}
+
+	switch format {
+	case ExportFormatCsv:
+		csvWriter.Flush()
+	case ExportFormatJson:
+		ctx.Writer.WriteString("]")
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/import [post]
+// @Summary Import urls
+// @Description Import urls from a file made by the export endpoint, a bulk csv or a Bitly csv export.
+// @Description Custom slugs are kept when they are free, taken ones are reported with URL_UNAVAILABLE.
+// @Description Exported links which ran out of clicks are refused with CLICKS_EXHAUSTED and protected
+// @Description ones with PASSWORD_REQUIRED unless the row is given a password, exports have none.
+// @Tags url
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "File"
+// @Param format query string false "Format" Enums(csv, json, ndjson, bitly)
+// @Success 200 {object} models.BulkCreateResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+func (h *handlerV1) ImportUrls(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rows, err := readImportRows(fileHeader, ctx.DefaultQuery("format", ExportFormatCsv))
+	if err != nil {
+		h.logger.WithError(err).Error("failed to parse import file")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if len(rows) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrBadRequest))
+		return
+	}
+	if !h.rateLimit(ctx, CreateRowsLimit, h.cfg.RateLimit.CreateRows, len(rows)) {
+		return
+	}
+
+	response, err := h.createBulk(rows, payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to import urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func readImportRows(fileHeader *multipart.FileHeader, format string) ([]*bulkRow, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := parseImportRows(file, format)
+	if err != nil {
+		return nil, err
+	}
+	checkExportedRows(rows)
+
+	return rows, nil
+}
+
+// checkExportedRows refuses rows which would not be imported as they were
+// exported. Links which ran out of clicks have max_clicks 0, which means
+// no limit, and passwords of protected links are not exported.
+func checkExportedRows(rows []*bulkRow) {
+	for _, row := range rows {
+		switch {
+		case row.err != nil:
+		case row.exhausted:
+			row.err = ErrClicksExhausted
+		case row.protected && row.req.Password == "":
+			row.err = ErrPasswordRequired
+		}
+	}
+}
+
+func parseImportRows(file io.Reader, format string) ([]*bulkRow, error) {
+	switch format {
+	case ExportFormatCsv:
+		return readCsvRows(file, ImportMaxRows, nil)
+	case ImportFormatBitly:
+		rows, err := readCsvRows(file, ImportMaxRows, bitlyColumns)
+		if err != nil {
+			return nil, err
+		}
+		// custom links of Bitly are full links like brand.co/sale
+		for _, row := range rows {
+			row.req.CustomUrl = linkSlug(row.req.CustomUrl)
+		}
+		return rows, nil
+	case ExportFormatJson:
+		var reqs []*importUrl
+		if err := json.NewDecoder(file).Decode(&reqs); err != nil {
+			return nil, err
+		}
+		if len(reqs) > ImportMaxRows {
+			return nil, tooManyRows(ImportMaxRows)
+		}
+		return importRows(reqs), nil
+	case ExportFormatNdjson:
+		var reqs []*importUrl
+		decoder := json.NewDecoder(file)
+		for {
+			var req importUrl
+			err := decoder.Decode(&req)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if len(reqs) == ImportMaxRows {
+				return nil, tooManyRows(ImportMaxRows)
+			}
+			reqs = append(reqs, &req)
+		}
+		return importRows(reqs), nil
+	}
+
+	return nil, ErrInvalidFormat
+}
+
+func importRows(reqs []*importUrl) []*bulkRow {
+	rows := make([]*bulkRow, 0, len(reqs))
+	for _, req := range reqs {
+		if req == nil {
+			rows = append(rows, &bulkRow{err: ErrBadRequest})
+			continue
+		}
+		row := bulkRow{
+			req:       &req.CreateShortUrlRequest,
+			protected: req.Protected,
+		}
+		if req.MaxClicks != nil {
+			row.req.MaxClicks = *req.MaxClicks
+			row.exhausted = *req.MaxClicks == 0
+		}
+		rows = append(rows, &row)
+	}
+
+	return rows
+}
+
+// linkSlug turns short links like "https://bit.ly/abc" into their slug,
+// only the first of several comma separated links is used
+func linkSlug(link string) string {
+	if i := strings.IndexAny(link, ", "); i >= 0 {
+		link = link[:i]
+	}
+	link = strings.TrimRight(link, "/")
+	if i := strings.LastIndex(link, "/"); i >= 0 {
+		link = link[i+1:]
+	}
+	return link
+}
+
+func exportCsvRecord(u *models.ExportUrl) []string {
+	formatInt := func(v int64) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatInt(v, 10)
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	tagIds := make([]string, 0, len(u.TagIds))
+	for _, id := range u.TagIds {
+		tagIds = append(tagIds, strconv.FormatInt(id, 10))
+	}
+	maxClicks := ""
+	if u.MaxClicks != nil {
+		maxClicks = strconv.FormatInt(*u.MaxClicks, 10)
+	}
+
+	return []string{
+		strconv.FormatInt(u.Id, 10),
+		u.OriginalUrl,
+		u.CustomUrl,
+		u.ShortUrl,
+		formatInt(u.DomainId),
+		formatInt(u.FolderId),
+		strings.Join(tagIds, ";"),
+		maxClicks,
+		formatTime(u.StartsAt),
+		formatTime(u.ExpiresAt),
+		strconv.FormatBool(u.Active),
+		u.InactiveReason,
+		u.FallbackUrl,
+		u.Preview.Title,
+		u.Preview.Description,
+		u.Preview.Image,
+		strconv.FormatBool(u.Protected),
+		strconv.FormatInt(u.Clicks, 10),
+		u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func parseExportModel(data *repo.Url, shortUrl string) *models.ExportUrl {
+	return &models.ExportUrl{
+		Id:             data.Id,
+		OriginalUrl:    data.OriginalUrl,
+		CustomUrl:      data.HashedUrl,
+		ShortUrl:       shortUrl,
+		DomainId:       data.DomainId,
+		FolderId:       data.FolderId,
+		TagIds:         data.TagIds,
+		MaxClicks:      data.MaxClicks,
+		StartsAt:       data.StartsAt,
+		ExpiresAt:      data.ExpiresAt,
+		Active:         data.Active,
+		InactiveReason: data.InactiveReason,
+		FallbackUrl:    data.FallbackUrl,
+		Preview:        parsePreviewModel(data),
+		Protected:      data.Password != "",
+		Clicks:         data.Clicks,
+		CreatedAt:      data.CreatedAt,
+	}
+}
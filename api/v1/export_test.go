@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportExportedUrls(t *testing.T) {
+	limit := int64(5)
+	exhausted := int64(0)
+	urls := []*repo.Url{
+		{Id: 1, OriginalUrl: "https://example.com/a", HashedUrl: "a", MaxClicks: &limit},
+		{Id: 2, OriginalUrl: "https://example.com/b", HashedUrl: "b", MaxClicks: &exhausted},
+		{Id: 3, OriginalUrl: "https://example.com/c", HashedUrl: "c", Password: "hash"},
+		{Id: 4, OriginalUrl: "https://example.com/d", HashedUrl: "d"},
+	}
+
+	export := func(format string) *bytes.Buffer {
+		var buf bytes.Buffer
+		csvWriter := csv.NewWriter(&buf)
+		if format == ExportFormatCsv {
+			require.NoError(t, csvWriter.Write(exportCsvHeader))
+		}
+		items := make([]interface{}, 0, len(urls))
+		for _, u := range urls {
+			item := parseExportModel(u, "http://localhost/"+u.HashedUrl)
+			switch format {
+			case ExportFormatCsv:
+				require.NoError(t, csvWriter.Write(exportCsvRecord(item)))
+			case ExportFormatNdjson:
+				require.NoError(t, json.NewEncoder(&buf).Encode(item))
+			}
+			items = append(items, item)
+		}
+		if format == ExportFormatJson {
+			require.NoError(t, json.NewEncoder(&buf).Encode(items))
+		}
+		csvWriter.Flush()
+		return &buf
+	}
+
+	for _, format := range []string{ExportFormatCsv, ExportFormatJson, ExportFormatNdjson} {
+		t.Run(format, func(t *testing.T) {
+			rows, err := parseImportRows(export(format), format)
+			require.NoError(t, err)
+			checkExportedRows(rows)
+			require.Len(t, rows, 4)
+
+			require.NoError(t, rows[0].err)
+			require.Equal(t, "a", rows[0].req.CustomUrl)
+			require.Equal(t, int64(5), rows[0].req.MaxClicks)
+
+			// max_clicks 0 would come back as a link without a limit
+			require.ErrorIs(t, rows[1].err, ErrClicksExhausted)
+			// and protected links without their password
+			require.ErrorIs(t, rows[2].err, ErrPasswordRequired)
+
+			require.NoError(t, rows[3].err)
+			require.Zero(t, rows[3].req.MaxClicks)
+		})
+	}
+
+	// protected rows are imported once they are given a password
+	rows, err := parseImportRows(bytes.NewBufferString(`{"original_url": "https://example.com/c", "protected": true, "password": "secret"}`), ExportFormatNdjson)
+	require.NoError(t, err)
+	checkExportedRows(rows)
+	require.NoError(t, rows[0].err)
+	require.Equal(t, "secret", rows[0].req.Password)
+}
@@ -0,0 +1,235 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /folders [post]
+// @Summary Create a folder
+// @Description Create a folder to keep your urls in
+// @Tags folder
+// @Accept json
+// @Produce json
+// @Param data body models.FolderRequest true "Data"
+// @Success 201 {object} models.Folder
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) CreateFolder(ctx *gin.Context) {
+	var (
+		req models.FolderRequest
+	)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to folder")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	name, err := validateName(req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	folder, err := h.storage.Folder().Create(&repo.Folder{
+		UserId: payload.UserID,
+		Name:   name,
+	})
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrFolderExists))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to create folder")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, parseFolderModel(folder))
+}
+
+// @Security ApiKeyAuth
+// @Router /folders [get]
+// @Summary Get your folders
+// @Description Get all folders of the current user with the number of their urls
+// @Tags folder
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllFoldersResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetAllFolders(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	folders, err := h.storage.Folder().GetAll(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get folders")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetAllFoldersResponse{
+		Folders: make([]*models.Folder, 0),
+		Count:   int32(len(folders)),
+	}
+	for _, f := range folders {
+		response.Folders = append(response.Folders, parseFolderModel(f))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /folders/{id} [put]
+// @Summary Rename a folder
+// @Description Rename a folder, its urls stay in it
+// @Tags folder
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.FolderRequest true "Data"
+// @Success 200 {object} models.Folder
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) UpdateFolder(ctx *gin.Context) {
+	var (
+		req models.FolderRequest
+	)
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	name, err := validateName(req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	folder, err := h.storage.Folder().Update(&repo.Folder{
+		Id:     int64(id),
+		UserId: payload.UserID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		return
+	} else if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrFolderExists))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to update folder")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseFolderModel(folder))
+}
+
+// @Security ApiKeyAuth
+// @Router /folders/{id} [delete]
+// @Summary Delete folder by id
+// @Description Delete folder by id, its urls are kept without a folder
+// @Tags folder
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) DeleteFolder(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	err = h.storage.Folder().Delete(int64(id), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to delete folder")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// checkFolder makes sure the folder exists and belongs to the user,
+// 0 means no folder
+func (h *handlerV1) checkFolder(folderID, userID int64) error {
+	if folderID == 0 {
+		return nil
+	}
+
+	folder, err := h.storage.Folder().Get(folderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidFolder
+		}
+		return err
+	}
+	if folder.UserId != userID {
+		return ErrInvalidFolder
+	}
+
+	return nil
+}
+
+func parseFolderModel(folder *repo.Folder) *models.Folder {
+	return &models.Folder{
+		Id:        folder.Id,
+		UserId:    folder.UserId,
+		Name:      folder.Name,
+		Urls:      folder.Urls,
+		CreatedAt: folder.CreatedAt,
+	}
+}
@@ -1,14 +1,23 @@
 package v1
 
 import (
+	"context"
 	"errors"
+	"net"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/SaidovZohid/competition-project/api/models"
 	"github.com/SaidovZohid/competition-project/config"
 	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/qr"
+	"github.com/SaidovZohid/competition-project/pkg/ratelimit"
+	"github.com/SaidovZohid/competition-project/pkg/slug"
 	"github.com/SaidovZohid/competition-project/pkg/token"
 	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/SaidovZohid/competition-project/worker"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,6 +25,7 @@ var (
 	ErrWrongEmailOrPassword = errors.New("INVALID_EMAIL_OR_PASSWORD")
 	ErrInternalServer       = errors.New("INTERNAL_SERVER_ERROR")
 	ErrNotFound             = errors.New("NOT_FOUND")
+	ErrGone                 = errors.New("GONE")
 	ErrUnknownUser          = errors.New("UNKNOWN_USER")
 	ErrEmailExists          = errors.New("EMAIL_EXISTS")
 	ErrMemberExists         = errors.New("MEMBER_IN_COMPANY")
@@ -32,31 +42,81 @@ var (
 	ErrWrongEmailOrPass     = errors.New("wrong email or password")
 	ErrWeakPassword         = errors.New("password must contain at least one small letter, one number and be at least 6 characters long")
 	ErrUrlUnavailable       = errors.New("URL_UNAVAILABLE")
+	ErrInvalidInterval      = errors.New("INVALID_INTERVAL")
+	ErrInvalidDomain        = errors.New("INVALID_DOMAIN")
+	ErrDomainExists         = errors.New("DOMAIN_EXISTS")
+	ErrDomainInUse          = errors.New("DOMAIN_IN_USE")
+	ErrDomainNotVerified    = errors.New("DOMAIN_NOT_VERIFIED")
+	ErrInvalidUrl           = errors.New("INVALID_URL")
+	ErrPrivateUrl           = errors.New("PRIVATE_URL")
+	ErrInvalidSlug          = errors.New("INVALID_CUSTOM_URL")
+	ErrInvalidMaxClicks     = errors.New("INVALID_MAX_CLICKS")
+	ErrInvalidDuration      = errors.New("INVALID_DURATION")
+	ErrInvalidStatus        = errors.New("INVALID_STATUS")
+	ErrInvalidSort          = errors.New("INVALID_SORT")
+	ErrInvalidLinkPassword  = errors.New("INVALID_PASSWORD")
+	ErrInvalidStartsAt      = errors.New("INVALID_STARTS_AT")
+	ErrInvalidExpiresAt     = errors.New("INVALID_EXPIRES_AT")
+	ErrInvalidFallbackUrl   = errors.New("INVALID_FALLBACK_URL")
+	ErrInvalidPreview       = errors.New("INVALID_PREVIEW")
+	ErrInvalidPosition      = errors.New("INVALID_POSITION")
+	ErrInvalidOS            = errors.New("INVALID_OS")
+	ErrInvalidDevice        = errors.New("INVALID_DEVICE")
+	ErrInvalidTimeOfDay     = errors.New("INVALID_TIME_OF_DAY")
+	ErrInvalidTimezone      = errors.New("INVALID_TIMEZONE")
+	ErrInvalidName          = errors.New("INVALID_NAME")
+	ErrInvalidTag           = errors.New("INVALID_TAG")
+	ErrInvalidFolder        = errors.New("INVALID_FOLDER")
+	ErrTagExists            = errors.New("TAG_EXISTS")
+	ErrFolderExists         = errors.New("FOLDER_EXISTS")
+	ErrInvalidEvent         = errors.New("INVALID_EVENT")
+	ErrTooManyRequests      = errors.New("TOO_MANY_REQUESTS")
+	ErrInvalidApiKey        = errors.New("INVALID_API_KEY")
+	ErrApiKeyExpired        = errors.New("API_KEY_EXPIRED")
+	ErrInsufficientScope    = errors.New("INSUFFICIENT_SCOPE")
+	ErrInvalidScope         = errors.New("INVALID_SCOPE")
+	ErrInvalidRefreshToken  = errors.New("INVALID_REFRESH_TOKEN")
+	ErrRefreshTokenReused   = errors.New("REFRESH_TOKEN_REUSED")
+	ErrTokenRevoked         = errors.New("TOKEN_REVOKED")
 )
 
 type handlerV1 struct {
-	cfg        *config.Config
-	storage    storage.StorageI
-	inMemory   storage.InMemoryStorageI
-	tokenMaker token.Maker
-	logger     *logger.Logger
+	cfg             *config.Config
+	storage         storage.StorageI
+	inMemory        storage.InMemoryStorageI
+	tokenMaker      token.Maker
+	slugGenerator   slug.Generator
+	logger          *logger.Logger
+	metadataFetcher *worker.MetadataFetcher
+	webhookSender   *worker.WebhookSender
+	rateLimiter     *ratelimit.Limiter
+	// lookupTXT resolves the records domains are verified with
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
 }
 
 type HandlerV1Options struct {
-	Cfg        *config.Config
-	Storage    storage.StorageI
-	InMemory   storage.InMemoryStorageI
-	TokenMaker token.Maker
-	Logger     *logger.Logger
+	Cfg             *config.Config
+	Storage         storage.StorageI
+	InMemory        storage.InMemoryStorageI
+	TokenMaker      token.Maker
+	SlugGenerator   slug.Generator
+	Logger          *logger.Logger
+	MetadataFetcher *worker.MetadataFetcher
+	WebhookSender   *worker.WebhookSender
 }
 
 func New(options *HandlerV1Options) *handlerV1 {
 	return &handlerV1{
-		cfg:        options.Cfg,
-		storage:    options.Storage,
-		inMemory:   options.InMemory,
-		tokenMaker: options.TokenMaker,
-		logger:     options.Logger,
+		cfg:             options.Cfg,
+		storage:         options.Storage,
+		inMemory:        options.InMemory,
+		tokenMaker:      options.TokenMaker,
+		slugGenerator:   options.SlugGenerator,
+		logger:          options.Logger,
+		metadataFetcher: options.MetadataFetcher,
+		webhookSender:   options.WebhookSender,
+		rateLimiter:     ratelimit.New(options.InMemory),
+		lookupTXT:       net.DefaultResolver.LookupTXT,
 	}
 }
 
@@ -66,25 +126,21 @@ func errorResponse(err error) *models.ErrorResponse {
 	}
 }
 
-func validateUrlParams(ctx *gin.Context) (*models.CreateShortUrlRequest, error) {
-	var (
-		maxClicks int = 0
-		err       error
-	)
-
-	if ctx.Query("max_clicks") != "" {
-		maxClicks, err = strconv.Atoi(ctx.Query("max_clicks"))
+// parseIDList parses comma separated ids like "1,2,3"
+func parseIDList(s string) ([]int64, error) {
+	var ids []int64
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			return nil, err
 		}
+		ids = append(ids, id)
 	}
-
-	return &models.CreateShortUrlRequest{
-		OriginalUrl: ctx.Query("original_url"),
-		Duration:    ctx.Query("duration"),
-		MaxClicks:   int64(maxClicks),
-		CustomUrl:   ctx.Query("custom_url"),
-	}, nil
+	return ids, nil
 }
 
 func validateGetAllParams(c *gin.Context) (*models.GetAllParams, error) {
@@ -114,3 +170,142 @@ func validateGetAllParams(c *gin.Context) (*models.GetAllParams, error) {
 		Search: c.Query("search"),
 	}, nil
 }
+
+func validateGetAllUrlsParams(c *gin.Context) (*repo.GetAllUrlsParams, error) {
+	params, err := validateGetAllParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	result := repo.GetAllUrlsParams{
+		Limit:  params.Limit,
+		Page:   params.Page,
+		Search: params.Search,
+		Status: c.Query("status"),
+		SortBy: c.Query("sort_by"),
+	}
+
+	switch result.Status {
+	case "", repo.UrlStatusActive, repo.UrlStatusScheduled, repo.UrlStatusExpired, repo.UrlStatusExhausted:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	switch result.SortBy {
+	case "", "created_at", "clicks", "expires_at":
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		result.Ascending = true
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	if c.Query("tag_id") != "" {
+		result.TagID, err = strconv.ParseInt(c.Query("tag_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Query("folder_id") != "" {
+		result.FolderID, err = strconv.ParseInt(c.Query("folder_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Query("from") != "" {
+		from, err := time.Parse(time.RFC3339, c.Query("from"))
+		if err != nil {
+			return nil, err
+		}
+		result.From = &from
+	}
+
+	if c.Query("to") != "" {
+		to, err := time.Parse(time.RFC3339, c.Query("to"))
+		if err != nil {
+			return nil, err
+		}
+		result.To = &to
+	}
+
+	return &result, nil
+}
+
+func validateStatsParams(c *gin.Context) (*repo.GetClickStatsParams, error) {
+	var (
+		interval = "day"
+		to       = time.Now()
+		from     = to.AddDate(0, 0, -30)
+		err      error
+	)
+
+	if c.Query("interval") != "" {
+		interval = c.Query("interval")
+	}
+	if interval != "hour" && interval != "day" && interval != "week" {
+		return nil, ErrInvalidInterval
+	}
+
+	if c.Query("from") != "" {
+		from, err = time.Parse(time.RFC3339, c.Query("from"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Query("to") != "" {
+		to, err = time.Parse(time.RFC3339, c.Query("to"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &repo.GetClickStatsParams{
+		Interval: interval,
+		From:     from,
+		To:       to,
+	}, nil
+}
+
+func validateQrParams(c *gin.Context) (*qr.Options, error) {
+	var (
+		size      int
+		quietZone bool = true
+		err       error
+	)
+
+	if c.Query("size") != "" {
+		size, err = strconv.Atoi(c.Query("size"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Query("quiet_zone") != "" {
+		quietZone, err = strconv.ParseBool(c.Query("quiet_zone"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	options := qr.Options{
+		Format:     c.Query("format"),
+		Size:       size,
+		Level:      c.Query("level"),
+		Foreground: c.Query("fg"),
+		Background: c.Query("bg"),
+		QuietZone:  quietZone,
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &options, nil
+}
@@ -0,0 +1,232 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// pages are rendered from templates of the repository root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeStorage implements the repos the redirect path uses, calling any
+// other method panics
+type fakeStorage struct {
+	storage.StorageI
+	urls         *fakeUrlRepo
+	clicks       *fakeClickRepo
+	domains      map[int64]*repo.Domain
+	rules        map[int64][]*repo.Rule
+	destinations map[int64][]*repo.Destination
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		urls:         &fakeUrlRepo{urls: make(map[string]*repo.Url)},
+		clicks:       &fakeClickRepo{created: make(chan *repo.Click, 10)},
+		domains:      make(map[int64]*repo.Domain),
+		rules:        make(map[int64][]*repo.Rule),
+		destinations: make(map[int64][]*repo.Destination),
+	}
+}
+
+func (s *fakeStorage) Url() repo.UrlStorageI {
+	return s.urls
+}
+
+func (s *fakeStorage) Click() repo.ClickStorageI {
+	return s.clicks
+}
+
+func (s *fakeStorage) Domain() repo.DomainStorageI {
+	return fakeDomainRepo{domains: s.domains}
+}
+
+func (s *fakeStorage) Rule() repo.RuleStorageI {
+	return fakeRuleRepo{rules: s.rules}
+}
+
+func (s *fakeStorage) Destination() repo.DestinationStorageI {
+	return fakeDestinationRepo{destinations: s.destinations}
+}
+
+type fakeUrlRepo struct {
+	repo.UrlStorageI
+	mu   sync.Mutex
+	urls map[string]*repo.Url
+	gets int
+}
+
+func (r *fakeUrlRepo) Get(slug string, domainID int64) (*repo.Url, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gets++
+	url, ok := r.urls[slug]
+	if !ok || url.DomainId != domainID {
+		return nil, sql.ErrNoRows
+	}
+	u := *url
+	return &u, nil
+}
+
+func (r *fakeUrlRepo) ConsumeClick(id int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, url := range r.urls {
+		if url.Id == id && url.MaxClicks != nil && *url.MaxClicks > 0 {
+			remaining := *url.MaxClicks - 1
+			url.MaxClicks = &remaining
+			return remaining, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+type fakeClickRepo struct {
+	repo.ClickStorageI
+	created chan *repo.Click
+}
+
+func (r *fakeClickRepo) Create(c *repo.Click) (*repo.Click, error) {
+	r.created <- c
+	return c, nil
+}
+
+type fakeDomainRepo struct {
+	repo.DomainStorageI
+	domains map[int64]*repo.Domain
+}
+
+func (r fakeDomainRepo) Get(id int64) (*repo.Domain, error) {
+	domain, ok := r.domains[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	d := *domain
+	return &d, nil
+}
+
+func (r fakeDomainRepo) GetByHost(host string) (*repo.Domain, error) {
+	for _, domain := range r.domains {
+		if domain.Host == host && domain.VerifiedAt != nil {
+			return r.Get(domain.Id)
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r fakeDomainRepo) Verify(id int64) (*repo.Domain, error) {
+	domain, ok := r.domains[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if domain.VerifiedAt == nil {
+		now := time.Now()
+		domain.VerifiedAt = &now
+	}
+	return r.Get(id)
+}
+
+type fakeRuleRepo struct {
+	repo.RuleStorageI
+	rules map[int64][]*repo.Rule
+}
+
+func (r fakeRuleRepo) GetAll(urlID int64) ([]*repo.Rule, error) {
+	return r.rules[urlID], nil
+}
+
+type fakeDestinationRepo struct {
+	repo.DestinationStorageI
+	destinations map[int64][]*repo.Destination
+}
+
+func (r fakeDestinationRepo) GetAll(urlID int64) ([]*repo.Destination, error) {
+	return r.destinations[urlID], nil
+}
+
+// fakeInMemory keeps values in a map and ignores expirations
+type fakeInMemory struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newFakeInMemory() *fakeInMemory {
+	return &fakeInMemory{values: make(map[string]string)}
+}
+
+func (m *fakeInMemory) Set(key, value string, exp time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *fakeInMemory) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func (m *fakeInMemory) Del(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+func (m *fakeInMemory) Exists(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.values[key]
+	return ok, nil
+}
+
+func (m *fakeInMemory) Incr(key string, exp time.Duration) (int64, error) {
+	return m.IncrBy(key, 1, exp)
+}
+
+func (m *fakeInMemory) IncrBy(key string, n int64, exp time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, _ := strconv.ParseInt(m.values[key], 10, 64)
+	value += n
+	m.values[key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func newTestHandler(strg storage.StorageI, inMemory storage.InMemoryStorageI) *handlerV1 {
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	return New(&HandlerV1Options{
+		Cfg: &config.Config{
+			PublicBaseUrl: "http://localhost",
+			IpHashSecret:  "secret",
+		},
+		Storage:  strg,
+		InMemory: inMemory,
+		Logger:   &log,
+	})
+}
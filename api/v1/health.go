@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /urls/broken [get]
+// @Summary Get your broken urls
+// @Description Get active urls whose destination failed the last HEALTH_CHECK_BROKEN_AFTER checks in a row,
+// @Description the ones broken for the longest time come first
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllParams false "Filter"
+// @Success 200 {object} models.GetBrokenUrlsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetBrokenUrls(ctx *gin.Context) {
+	params, err := validateGetAllParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	result, err := h.storage.Health().GetBroken(&repo.GetBrokenUrlsParams{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		UserID:    payload.UserID,
+		Threshold: h.cfg.HealthCheck.BrokenAfter,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get broken urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetBrokenUrlsResponse{
+		Urls:  make([]*models.BrokenUrl, 0),
+		Count: result.Count,
+	}
+	for _, b := range result.Urls {
+		shortUrl, err := h.shortUrl(b.Url)
+		if err != nil {
+			h.logger.WithError(err).Error("failed to build short url")
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+			return
+		}
+		response.Urls = append(response.Urls, &models.BrokenUrl{
+			Url:    parseUrlModel(b.Url, shortUrl),
+			Health: parseHealthModel(b.Health),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func parseHealthModel(h *repo.UrlHealth) *models.UrlHealth {
+	return &models.UrlHealth{
+		StatusCode:          h.StatusCode,
+		LatencyMs:           h.Latency.Milliseconds(),
+		FinalUrl:            h.FinalUrl,
+		Error:               h.Error,
+		ConsecutiveFailures: h.ConsecutiveFailures,
+		BrokenSince:         h.BrokenSince,
+		CheckedAt:           h.CheckedAt,
+	}
+}
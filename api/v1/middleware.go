@@ -1,11 +1,14 @@
 package v1
 
 import (
+	"database/sql"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/apikey"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,9 +18,19 @@ type Payload struct {
 	Email     string `json:"email"`
 	IssuedAt  string `json:"issued_at"`
 	ExpiredAt string `json:"expired_at"`
+	// ApiKeyId and Scopes are set for requests made with an api key,
+	// access tokens have every scope
+	ApiKeyId int64    `json:"api_key_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
 }
 
+// AuthMiddleware accepts an access token in the authorization header or
+// an api key in the X-API-Key header
 func (h *handlerV1) AuthMiddleware(ctx *gin.Context) {
+	if key := ctx.GetHeader(apikey.Header); key != "" {
+		h.apiKeyAuth(ctx, key)
+		return
+	}
 
 	accessToken := ctx.GetHeader(h.cfg.AuthHeaderKey)
 
@@ -34,6 +47,17 @@ func (h *handlerV1) AuthMiddleware(ctx *gin.Context) {
 		return
 	}
 
+	revoked, err := h.isRevoked(payload)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to check token revocation")
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	if revoked {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ErrTokenRevoked))
+		return
+	}
+
 	ctx.Set(h.cfg.AuthPayloadKey, Payload{
 		Id:        payload.ID,
 		UserID:    payload.UserID,
@@ -44,6 +68,155 @@ func (h *handlerV1) AuthMiddleware(ctx *gin.Context) {
 	ctx.Next()
 }
 
+func (h *handlerV1) apiKeyAuth(ctx *gin.Context, key string) {
+	if !apikey.Valid(key) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ErrInvalidApiKey))
+		return
+	}
+
+	k, err := h.storage.ApiKey().GetByHash(apikey.Hash(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ErrInvalidApiKey))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to get api key")
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	if k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ErrApiKeyExpired))
+		return
+	}
+
+	go func() {
+		if err := h.storage.ApiKey().Touch(k.Id); err != nil {
+			h.logger.WithError(err).Error("failed to touch api key")
+		}
+	}()
+
+	ctx.Set(h.cfg.AuthPayloadKey, Payload{
+		UserID:   k.UserId,
+		ApiKeyId: k.Id,
+		Scopes:   k.Scopes,
+	})
+	ctx.Next()
+}
+
+// RequireScope lets requests made with an api key through only when the
+// key has the scope, it goes after AuthMiddleware
+func (h *handlerV1) RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload, err := h.GetAuthPayload(ctx)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+			return
+		}
+
+		if payload.ApiKeyId != 0 && !hasScope(payload.Scopes, scope) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrInsufficientScope))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// SessionOnly rejects requests made with an api key, it guards account
+// settings which no scope covers
+func (h *handlerV1) SessionOnly(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	if payload.ApiKeyId != 0 {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrInsufficientScope))
+		return
+	}
+	ctx.Next()
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimit allows limit requests per configured window to the route.
+// Requests are counted per user after AuthMiddleware and per client ip
+// otherwise, name keeps the counters of different limits apart. When redis
+// fails requests are let through, limiting is not worth an outage.
+func (h *handlerV1) RateLimit(name string, limit int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !h.rateLimit(ctx, name, limit, 1) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// rateLimit counts n requests against the named limit, handlers use it to
+// count what they only know after reading the body. It responds with 429
+// and returns false when the limit is exceeded.
+func (h *handlerV1) rateLimit(ctx *gin.Context, name string, limit, n int) bool {
+	key := name + "_ip_" + h.hashIP(ctx.ClientIP())
+	if i, exists := ctx.Get(h.cfg.AuthPayloadKey); exists {
+		if payload, ok := i.(Payload); ok {
+			key = name + "_user_" + strconv.FormatInt(payload.UserID, 10)
+		}
+	}
+
+	result, err := h.rateLimiter.AllowN(key, n, limit, h.cfg.RateLimit.Window)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to check rate limit")
+		return true
+	}
+
+	// with several limits on a route clients see the tightest one
+	header := ctx.Writer.Header()
+	reset := strconv.Itoa(int(result.Reset.Round(time.Second) / time.Second))
+	remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining"))
+	if err != nil || result.Remaining <= remaining {
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", reset)
+	}
+
+	if !result.Allowed {
+		header.Set("Retry-After", reset)
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(ErrTooManyRequests))
+		return false
+	}
+	return true
+}
+
+// AuthFailureLimit goes in front of AuthMiddleware and rejects client ips
+// which failed to authenticate more than limit times per window, so api
+// keys and tokens can not be guessed. Only failed requests are counted.
+func (h *handlerV1) AuthFailureLimit(limit int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := "auth_failure_ip_" + h.hashIP(ctx.ClientIP())
+
+		result := h.rateLimiter.Check(key, limit, h.cfg.RateLimit.Window)
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(result.Reset.Round(time.Second)/time.Second)))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(ErrTooManyRequests))
+			return
+		}
+
+		ctx.Next()
+
+		if ctx.Writer.Status() == http.StatusUnauthorized {
+			if _, err := h.rateLimiter.Allow(key, limit, h.cfg.RateLimit.Window); err != nil {
+				h.logger.WithError(err).Error("failed to count auth failure")
+			}
+		}
+	}
+}
+
 func (h *handlerV1) GetAuthPayload(ctx *gin.Context) (*Payload, error) {
 	i, exists := ctx.Get(h.cfg.AuthPayloadKey)
 	if !exists {
@@ -62,6 +235,8 @@ func (h *handlerV1) GetAuthPayload(ctx *gin.Context) (*Payload, error) {
 		Email:     payload.Email,
 		IssuedAt:  payload.IssuedAt,
 		ExpiredAt: payload.ExpiredAt,
+		ApiKeyId:  payload.ApiKeyId,
+		Scopes:    payload.Scopes,
 	}, nil
 }
 
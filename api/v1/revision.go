@@ -0,0 +1,189 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/history [get]
+// @Summary Get url history
+// @Description Get changes of destination, slug, limits and schedule of the url, the newest first
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.UrlHistoryResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) GetUrlHistory(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.storage.Revision().GetAll(url.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get revisions")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.UrlHistoryResponse{
+		Revisions: make([]*models.UrlRevision, 0),
+		Count:     int32(len(revisions)),
+	}
+	for i, r := range revisions {
+		// revisions are ordered newest first, the previous one comes next
+		var prev *repo.UrlRevision
+		if i+1 < len(revisions) {
+			prev = revisions[i+1]
+		}
+		response.Revisions = append(response.Revisions, parseRevisionModel(r, prev))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/revert/{revision} [post]
+// @Summary Revert url
+// @Description Bring back destination, slug and schedule of the revision, the revert is kept as a new revision.
+// @Description The clicks left are kept, clicks which were used since the revision are not given back.
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param revision path int true "Revision"
+// @Success 200 {object} models.Url
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) RevertUrl(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	revisionNumber, err := strconv.Atoi(ctx.Param("revision"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	revision, err := h.storage.Revision().Get(url.Id, revisionNumber)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to get revision")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	resp, err := h.storage.Url().Update(&repo.Url{
+		Id:          url.Id,
+		UserId:      url.UserId,
+		OriginalUrl: revision.OriginalUrl,
+		HashedUrl:   revision.HashedUrl,
+		// revisions keep the clicks left at their time, bringing them
+		// back would refill clicks which were used since
+		MaxClicks:   url.MaxClicks,
+		StartsAt:    revision.StartsAt,
+		ExpiresAt:   revision.ExpiresAt,
+		Password:    url.Password,
+		FolderId:    url.FolderId,
+		FallbackUrl: url.FallbackUrl,
+
+		PreviewTitle:       url.PreviewTitle,
+		PreviewDescription: url.PreviewDescription,
+		PreviewImage:       url.PreviewImage,
+	}, payload.UserID)
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrUrlUnavailable))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to revert url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	h.invalidateUrl(url, resp)
+	h.fetchMetadata(resp)
+	h.notify(webhook.EventUrlUpdated, resp)
+
+	shortUrl, err := h.shortUrl(resp)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build short url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseUrlModel(resp, shortUrl))
+}
+
+func parseRevisionModel(r, prev *repo.UrlRevision) *models.UrlRevision {
+	result := models.UrlRevision{
+		Revision:    r.Revision,
+		UserId:      r.UserId,
+		OriginalUrl: r.OriginalUrl,
+		HashedUrl:   r.HashedUrl,
+		MaxClicks:   r.MaxClicks,
+		StartsAt:    r.StartsAt,
+		ExpiresAt:   r.ExpiresAt,
+		Changes:     make([]string, 0),
+		CreatedAt:   r.CreatedAt,
+	}
+	if prev == nil {
+		return &result
+	}
+
+	if r.OriginalUrl != prev.OriginalUrl {
+		result.Changes = append(result.Changes, "original_url")
+	}
+	if r.HashedUrl != prev.HashedUrl {
+		result.Changes = append(result.Changes, "hashed_url")
+	}
+	if !equalInt64Ptr(r.MaxClicks, prev.MaxClicks) {
+		result.Changes = append(result.Changes, "max_clicks")
+	}
+	if !equalTimePtr(r.StartsAt, prev.StartsAt) {
+		result.Changes = append(result.Changes, "starts_at")
+	}
+	if !equalTimePtr(r.ExpiresAt, prev.ExpiresAt) {
+		result.Changes = append(result.Changes, "expires_at")
+	}
+
+	return &result
+}
+
+func equalInt64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,474 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/useragent"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const RulesCacheKey = "url_rules_"
+
+var (
+	ruleOSes = map[string]string{
+		strings.ToLower(useragent.OSIOS):      useragent.OSIOS,
+		strings.ToLower(useragent.OSAndroid):  useragent.OSAndroid,
+		strings.ToLower(useragent.OSWindows):  useragent.OSWindows,
+		strings.ToLower(useragent.OSMacOS):    useragent.OSMacOS,
+		strings.ToLower(useragent.OSChromeOS): useragent.OSChromeOS,
+		strings.ToLower(useragent.OSLinux):    useragent.OSLinux,
+		strings.ToLower(useragent.OSOther):    useragent.OSOther,
+	}
+	ruleDevices = map[string]bool{
+		useragent.DeviceMobile:  true,
+		useragent.DeviceTablet:  true,
+		useragent.DeviceDesktop: true,
+		useragent.DeviceBot:     true,
+	}
+)
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/rules [post]
+// @Summary Add redirect rule
+// @Description Add a rule which sends matching visitors to its own target url
+// @Tags rule
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.UrlRuleRequest true "Data"
+// @Success 201 {object} models.UrlRule
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) CreateUrlRule(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.UrlRuleRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to rule")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rule, err := prepareRule(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	rule.UrlId = url.Id
+
+	rule, err = h.storage.Rule().Create(rule)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to create rule")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	h.invalidateRules(url.Id)
+
+	ctx.JSON(http.StatusCreated, parseRuleModel(rule))
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/rules [get]
+// @Summary Get redirect rules
+// @Description Get rules of the url in the order they are evaluated
+// @Tags rule
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.GetUrlRulesResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) GetUrlRules(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	rules, err := h.storage.Rule().GetAll(url.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get rules")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetUrlRulesResponse{
+		Rules:       make([]*models.UrlRule, 0),
+		Count:       int32(len(rules)),
+		FallbackUrl: url.OriginalUrl,
+	}
+	for _, r := range rules {
+		response.Rules = append(response.Rules, parseRuleModel(r))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/rules/{rule_id} [put]
+// @Summary Update redirect rule
+// @Description Update redirect rule, position 0 keeps the current position
+// @Tags rule
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param rule_id path int true "Rule ID"
+// @Param data body models.UrlRuleRequest true "Data"
+// @Success 200 {object} models.UrlRule
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) UpdateUrlRule(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	ruleID, err := strconv.Atoi(ctx.Param("rule_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req models.UrlRuleRequest
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to rule")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rule, err := prepareRule(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	rule.Id = int64(ruleID)
+	rule.UrlId = url.Id
+
+	rule, err = h.storage.Rule().Update(rule)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to update rule")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	h.invalidateRules(url.Id)
+
+	ctx.JSON(http.StatusOK, parseRuleModel(rule))
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/rules/{rule_id} [delete]
+// @Summary Delete redirect rule
+// @Description Delete redirect rule
+// @Tags rule
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param rule_id path int true "Rule ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) DeleteUrlRule(ctx *gin.Context) {
+	url, ok := h.ownUrlParam(ctx)
+	if !ok {
+		return
+	}
+
+	ruleID, err := strconv.Atoi(ctx.Param("rule_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Rule().Delete(int64(ruleID), url.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to delete rule")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	h.invalidateRules(url.Id)
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// ownUrlParam returns the url of the :id param if it belongs to the
+// current user, otherwise it writes the error response
+func (h *handlerV1) ownUrlParam(ctx *gin.Context) (*repo.Url, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return nil, false
+	}
+
+	url, err := h.getOwnUrl(int64(id), payload.UserID)
+	if err != nil {
+		h.ownUrlError(ctx, err)
+		return nil, false
+	}
+
+	return url, true
+}
+
+// redirectTarget returns the target of the first rule matching the
+// request, then one of the A/B destinations and the original url when
+// the url has neither. The id of the served destination is returned too.
+func (h *handlerV1) redirectTarget(ctx *gin.Context, url *repo.Url) (string, int64) {
+	rules, err := h.getRules(url.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get rules")
+	}
+
+	rule := matchRule(rules, ctx.Request.UserAgent(), ctx.GetHeader("Accept-Language"), time.Now())
+	if rule != nil {
+		return rule.TargetUrl, 0
+	}
+
+	destinations, err := h.getDestinations(url.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get destinations")
+	}
+	if len(destinations) > 0 {
+		d := h.pickDestination(ctx, url, destinations)
+		return d.TargetUrl, d.Id
+	}
+
+	return url.OriginalUrl, 0
+}
+
+// getRules caches rules next to the url, links without rules are cached
+// too, so they do not hit postgres on every redirect
+func (h *handlerV1) getRules(urlID int64) ([]*repo.Rule, error) {
+	key := RulesCacheKey + strconv.FormatInt(urlID, 10)
+
+	data, err := h.inMemory.Get(key)
+	if err == nil {
+		var rules []*repo.Rule
+		if err := json.Unmarshal([]byte(data), &rules); err == nil {
+			return rules, nil
+		}
+		h.logger.WithError(err).Error("failed to unmarshal cached rules")
+	}
+
+	rules, err := h.storage.Rule().GetAll(urlID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(rules)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to marshal rules")
+		return rules, nil
+	}
+	err = h.inMemory.Set(key, string(b), UrlCacheTTL)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to set rules to redis db")
+	}
+
+	return rules, nil
+}
+
+func (h *handlerV1) invalidateRules(urlID int64) {
+	err := h.inMemory.Del(RulesCacheKey + strconv.FormatInt(urlID, 10))
+	if err != nil {
+		h.logger.WithError(err).Error("failed to delete rules from redis db")
+	}
+}
+
+func matchRule(rules []*repo.Rule, ua, acceptLanguage string, now time.Time) *repo.Rule {
+	var (
+		os        = useragent.OS(ua)
+		device    = useragent.Device(ua)
+		languages = parseAcceptLanguage(acceptLanguage)
+	)
+
+	for _, r := range rules {
+		if r.OS != "" && r.OS != os {
+			continue
+		}
+		if r.Device != "" && r.Device != device {
+			continue
+		}
+		if r.Language != "" && !matchLanguage(r.Language, languages) {
+			continue
+		}
+		if r.TimeFrom != nil && r.TimeTo != nil && !matchTimeOfDay(r, now) {
+			continue
+		}
+		return r
+	}
+
+	return nil
+}
+
+// parseAcceptLanguage returns lower cased language tags of the header,
+// tags with q=0 are not acceptable and skipped
+func parseAcceptLanguage(header string) []string {
+	var tags []string
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if v, err := strconv.ParseFloat(params[2:], 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		tags = append(tags, strings.ToLower(tag))
+	}
+
+	return tags
+}
+
+// matchLanguage matches "en" with both "en" and "en-us", but "en-us"
+// only with "en-us"
+func matchLanguage(language string, tags []string) bool {
+	for _, tag := range tags {
+		if tag == language || strings.HasPrefix(tag, language+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTimeOfDay(r *repo.Rule, now time.Time) bool {
+	if r.Timezone != "" {
+		if loc, err := time.LoadLocation(r.Timezone); err == nil {
+			now = now.In(loc)
+		}
+	} else {
+		now = now.UTC()
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if *r.TimeFrom <= *r.TimeTo {
+		return minute >= *r.TimeFrom && minute < *r.TimeTo
+	}
+	// the window wraps midnight, e.g. 22:00-06:00
+	return minute >= *r.TimeFrom || minute < *r.TimeTo
+}
+
+func prepareRule(req *models.UrlRuleRequest) (*repo.Rule, error) {
+	if !isHttpUrl(req.TargetUrl) {
+		return nil, ErrInvalidUrl
+	}
+
+	if req.Position < 0 {
+		return nil, ErrInvalidPosition
+	}
+
+	rule := repo.Rule{
+		Position:  req.Position,
+		Language:  strings.ToLower(strings.TrimSpace(req.Language)),
+		Timezone:  req.Timezone,
+		TargetUrl: req.TargetUrl,
+	}
+
+	if req.OS != "" {
+		os, ok := ruleOSes[strings.ToLower(req.OS)]
+		if !ok {
+			return nil, ErrInvalidOS
+		}
+		rule.OS = os
+	}
+
+	if req.Device != "" {
+		rule.Device = strings.ToLower(req.Device)
+		if !ruleDevices[rule.Device] {
+			return nil, ErrInvalidDevice
+		}
+	}
+
+	if (req.TimeFrom == "") != (req.TimeTo == "") {
+		return nil, ErrInvalidTimeOfDay
+	}
+	if req.TimeFrom != "" {
+		from, err := parseTimeOfDay(req.TimeFrom)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseTimeOfDay(req.TimeTo)
+		if err != nil {
+			return nil, err
+		}
+		if from == to {
+			return nil, ErrInvalidTimeOfDay
+		}
+		rule.TimeFrom, rule.TimeTo = &from, &to
+	}
+
+	if rule.Timezone != "" {
+		if _, err := time.LoadLocation(rule.Timezone); err != nil {
+			return nil, ErrInvalidTimezone
+		}
+	}
+
+	return &rule, nil
+}
+
+// parseTimeOfDay turns HH:MM into minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, ErrInvalidTimeOfDay
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatTimeOfDay(minutes *int) string {
+	if minutes == nil {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d", *minutes/60, *minutes%60)
+}
+
+func parseRuleModel(rule *repo.Rule) *models.UrlRule {
+	return &models.UrlRule{
+		Id:        rule.Id,
+		UrlId:     rule.UrlId,
+		Position:  rule.Position,
+		OS:        rule.OS,
+		Device:    rule.Device,
+		Language:  rule.Language,
+		TimeFrom:  formatTimeOfDay(rule.TimeFrom),
+		TimeTo:    formatTimeOfDay(rule.TimeTo),
+		Timezone:  rule.Timezone,
+		TargetUrl: rule.TargetUrl,
+		CreatedAt: rule.CreatedAt,
+	}
+}
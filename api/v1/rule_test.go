@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/useragent"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	iphoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1"
+	windowsUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36"
+)
+
+func minutes(hour, minute int) *int {
+	m := hour*60 + minute
+	return &m
+}
+
+func TestMatchRule(t *testing.T) {
+	ios := &repo.Rule{Id: 1, OS: useragent.OSIOS}
+	mobileUz := &repo.Rule{Id: 2, Device: useragent.DeviceMobile, Language: "uz"}
+	office := &repo.Rule{Id: 3, TimeFrom: minutes(9, 0), TimeTo: minutes(18, 0)}
+	night := &repo.Rule{Id: 4, TimeFrom: minutes(22, 0), TimeTo: minutes(6, 0)}
+	tashkentNight := &repo.Rule{Id: 5, TimeFrom: minutes(22, 0), TimeTo: minutes(6, 0), Timezone: "Asia/Tashkent"}
+	english := &repo.Rule{Id: 6, Language: "en"}
+	englishUS := &repo.Rule{Id: 7, Language: "en-us"}
+
+	noon := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rules    []*repo.Rule
+		ua       string
+		language string
+		now      time.Time
+		want     *repo.Rule
+	}{
+		{"no rules", nil, iphoneUA, "", noon, nil},
+		{"os", []*repo.Rule{ios}, iphoneUA, "", noon, ios},
+		{"other os", []*repo.Rule{ios}, windowsUA, "", noon, nil},
+		{"first match wins", []*repo.Rule{office, ios}, iphoneUA, "", noon, office},
+		{"every condition has to match", []*repo.Rule{mobileUz}, iphoneUA, "en", noon, nil},
+		{"device and language", []*repo.Rule{mobileUz}, iphoneUA, "en;q=0.8, uz-UZ", noon, mobileUz},
+		{"language with q=0", []*repo.Rule{mobileUz}, iphoneUA, "uz;q=0, en", noon, nil},
+		{"language matches region", []*repo.Rule{english}, windowsUA, "en-GB", noon, english},
+		{"region does not match language", []*repo.Rule{englishUS}, windowsUA, "en", noon, nil},
+		{"inside window", []*repo.Rule{office}, windowsUA, "", noon, office},
+		{"window end is exclusive", []*repo.Rule{office}, windowsUA, "", time.Date(2023, 1, 1, 18, 0, 0, 0, time.UTC), nil},
+		{"window start is inclusive", []*repo.Rule{office}, windowsUA, "", time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC), office},
+		{"wrapping window before midnight", []*repo.Rule{night}, windowsUA, "", time.Date(2023, 1, 1, 23, 30, 0, 0, time.UTC), night},
+		{"wrapping window after midnight", []*repo.Rule{night}, windowsUA, "", time.Date(2023, 1, 1, 5, 59, 0, 0, time.UTC), night},
+		{"outside wrapping window", []*repo.Rule{night}, windowsUA, "", noon, nil},
+		{"wrapping window end is exclusive", []*repo.Rule{night}, windowsUA, "", time.Date(2023, 1, 1, 6, 0, 0, 0, time.UTC), nil},
+		// 18:00 utc is 23:00 in tashkent and noon is 17:00
+		{"window in timezone", []*repo.Rule{tashkentNight}, windowsUA, "", time.Date(2023, 1, 1, 18, 0, 0, 0, time.UTC), tashkentNight},
+		{"outside window in timezone", []*repo.Rule{tashkentNight}, windowsUA, "", noon, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, matchRule(tt.rules, tt.ua, tt.language, tt.now))
+		})
+	}
+}
+
+func TestRedirectRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	strg := newFakeStorage()
+	h := newTestHandler(strg, newFakeInMemory())
+
+	router := gin.New()
+	router.GET("/:shorturl", h.RedirectUrl)
+
+	strg.urls.urls["abc"] = &repo.Url{Id: 1, UserId: 1, OriginalUrl: "https://example.com", HashedUrl: "abc", Active: true}
+	strg.rules[1] = []*repo.Rule{
+		{Id: 1, UrlId: 1, OS: useragent.OSIOS, TargetUrl: "https://apps.apple.com/app"},
+	}
+
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{iphoneUA, "https://apps.apple.com/app"},
+		{windowsUA, "https://example.com"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.Header.Set("User-Agent", tt.ua)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusFound, w.Code)
+		require.Equal(t, tt.want, w.Header().Get("Location"))
+		<-strg.clicks.created
+	}
+}
@@ -0,0 +1,248 @@
+package v1
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/token"
+	"github.com/google/uuid"
+)
+
+const (
+	// RefreshTokenKey maps the hash of a refresh token to its session,
+	// RefreshUsedKey counts how often it was used
+	RefreshTokenKey = "refresh_token_"
+	RefreshUsedKey  = "refresh_token_used_"
+	SessionKey      = "session_"
+	// RevokedTokenKey marks access tokens by their id until they expire
+	RevokedTokenKey = "revoked_token_"
+	// SessionsRevokedKey holds the time before which every token of the
+	// user is revoked
+	SessionsRevokedKey = "sessions_revoked_at_"
+)
+
+// session is a chain of refresh tokens, every refresh replaces the token
+// and the access token issued with it
+type session struct {
+	UserID          int64     `json:"user_id"`
+	Email           string    `json:"email"`
+	AccessTokenID   string    `json:"access_token_id"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// startSession issues the first access and refresh tokens of a new session
+func (h *handlerV1) startSession(userID int64, email string) (*models.TokensResponse, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	return h.issueTokens(id.String(), &session{
+		UserID:    userID,
+		Email:     email,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (h *handlerV1) issueTokens(sessionID string, s *session) (*models.TokensResponse, error) {
+	accessToken, payload, err := h.tokenMaker.CreateToken(&token.TokenParams{
+		UserID:   s.UserID,
+		Email:    s.Email,
+		Duration: h.cfg.AccessTokenDuration,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	refreshToken := hex.EncodeToString(b)
+
+	s.AccessTokenID = payload.ID.String()
+	s.AccessExpiresAt = payload.ExpiresAt
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.inMemory.Set(SessionKey+sessionID, string(data), h.cfg.RefreshTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.inMemory.Set(RefreshTokenKey+hashToken(refreshToken), sessionID, h.cfg.RefreshTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokensResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// refreshSession swaps the refresh token for new tokens. Every refresh
+// token works once: when one is used again it must have been stolen, so
+// the whole session ends, tokens of the thief and of the user alike.
+func (h *handlerV1) refreshSession(refreshToken string) (*models.TokensResponse, error) {
+	hash := hashToken(refreshToken)
+
+	sessionID, err := h.inMemory.Get(RefreshTokenKey + hash)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	s, err := h.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	uses, err := h.inMemory.Incr(RefreshUsedKey+hash, h.cfg.RefreshTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+	if uses > 1 {
+		if err := h.endSession(sessionID, s); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	revokedAt, err := h.sessionsRevokedAt(s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if s.CreatedAt.Before(revokedAt) {
+		if err := h.endSession(sessionID, s); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return h.issueTokens(sessionID, s)
+}
+
+// getSession returns ErrInvalidRefreshToken for sessions which ended
+func (h *handlerV1) getSession(sessionID string) (*session, error) {
+	exists, err := h.inMemory.Exists(SessionKey + sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	data, err := h.inMemory.Get(SessionKey + sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var s session
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// endSession makes the refresh tokens and the last access token of the
+// session unusable
+func (h *handlerV1) endSession(sessionID string, s *session) error {
+	err := h.revokeToken(s.AccessTokenID, s.AccessExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return h.inMemory.Del(SessionKey + sessionID)
+}
+
+// logout ends the session of the refresh token when it belongs to the user
+func (h *handlerV1) logout(userID int64, refreshToken string) error {
+	sessionID, err := h.inMemory.Get(RefreshTokenKey + hashToken(refreshToken))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+
+	s, err := h.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if s.UserID != userID {
+		return ErrInvalidRefreshToken
+	}
+
+	return h.endSession(sessionID, s)
+}
+
+// revokeToken puts the access token on the revocation list until it
+// expires anyway
+func (h *handlerV1) revokeToken(id string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if id == "" || ttl <= 0 {
+		return nil
+	}
+
+	return h.inMemory.Set(RevokedTokenKey+id, "1", ttl)
+}
+
+// revokeSessions ends every session of the user, tokens issued before now
+// are rejected until the longest of them would have expired
+func (h *handlerV1) revokeSessions(userID int64) error {
+	ttl := h.cfg.RefreshTokenDuration
+	if h.cfg.AccessTokenDuration > ttl {
+		ttl = h.cfg.AccessTokenDuration
+	}
+
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return h.inMemory.Set(SessionsRevokedKey+strconv.FormatInt(userID, 10), now, ttl)
+}
+
+func (h *handlerV1) sessionsRevokedAt(userID int64) (time.Time, error) {
+	key := SessionsRevokedKey + strconv.FormatInt(userID, 10)
+
+	exists, err := h.inMemory.Exists(key)
+	if err != nil || !exists {
+		return time.Time{}, err
+	}
+
+	value, err := h.inMemory.Get(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, nanos), nil
+}
+
+// isRevoked reports whether the access token was revoked by logout, a
+// reused refresh token or a password change
+func (h *handlerV1) isRevoked(payload *models.AuthPayload) (bool, error) {
+	revoked, err := h.inMemory.Exists(RevokedTokenKey + payload.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revokedAt, err := h.sessionsRevokedAt(payload.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return payload.IssuedAt.Before(revokedAt), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
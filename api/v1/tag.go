@@ -0,0 +1,251 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const MaxNameLength = 50
+
+// @Security ApiKeyAuth
+// @Router /tags [post]
+// @Summary Create a tag
+// @Description Create a tag to label your urls with
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param data body models.TagRequest true "Data"
+// @Success 201 {object} models.Tag
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) CreateTag(ctx *gin.Context) {
+	var (
+		req models.TagRequest
+	)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to tag")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	name, err := validateName(req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tag, err := h.storage.Tag().Create(&repo.Tag{
+		UserId: payload.UserID,
+		Name:   name,
+	})
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrTagExists))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to create tag")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, parseTagModel(tag))
+}
+
+// @Security ApiKeyAuth
+// @Router /tags [get]
+// @Summary Get your tags
+// @Description Get all tags of the current user with the number of their urls and clicks
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllTagsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetAllTags(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	tags, err := h.storage.Tag().GetAll(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get tags")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetAllTagsResponse{
+		Tags:  make([]*models.Tag, 0),
+		Count: int32(len(tags)),
+	}
+	for _, t := range tags {
+		response.Tags = append(response.Tags, parseTagModel(t))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /tags/{id} [put]
+// @Summary Rename a tag
+// @Description Rename a tag, its urls keep it
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.TagRequest true "Data"
+// @Success 200 {object} models.Tag
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) UpdateTag(ctx *gin.Context) {
+	var (
+		req models.TagRequest
+	)
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	name, err := validateName(req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	tag, err := h.storage.Tag().Update(&repo.Tag{
+		Id:     int64(id),
+		UserId: payload.UserID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		return
+	} else if errors.Is(err, repo.ErrAlreadyExists) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrTagExists))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to update tag")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseTagModel(tag))
+}
+
+// @Security ApiKeyAuth
+// @Router /tags/{id} [delete]
+// @Summary Delete tag by id
+// @Description Delete tag by id, it is removed from its urls
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) DeleteTag(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	err = h.storage.Tag().Delete(int64(id), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to delete tag")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// checkTags makes sure every tag exists and belongs to the user
+func (h *handlerV1) checkTags(tagIDs []int64, userID int64) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	tags, err := h.storage.Tag().GetAll(userID)
+	if err != nil {
+		return err
+	}
+	own := make(map[int64]bool, len(tags))
+	for _, t := range tags {
+		own[t.Id] = true
+	}
+	for _, id := range tagIDs {
+		if !own[id] {
+			return ErrInvalidTag
+		}
+	}
+
+	return nil
+}
+
+// validateName trims names of tags and folders and checks their length
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+func parseTagModel(tag *repo.Tag) *models.Tag {
+	return &models.Tag{
+		Id:        tag.Id,
+		UserId:    tag.UserId,
+		Name:      tag.Name,
+		Urls:      tag.Urls,
+		Clicks:    tag.Clicks,
+		CreatedAt: tag.CreatedAt,
+	}
+}
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /urls/trash [get]
+// @Summary Get deleted urls
+// @Description Get your deleted urls with the time they can be restored until
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllParams false "Filter"
+// @Success 200 {object} models.GetAllUrlsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetTrash(ctx *gin.Context) {
+	params, err := validateGetAllParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	result, err := h.storage.Url().GetAll(&repo.GetAllUrlsParams{
+		Limit:   params.Limit,
+		Page:    params.Page,
+		Search:  params.Search,
+		UserID:  payload.UserID,
+		Deleted: true,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get deleted urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response, err := h.getUrlsResponse(result)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build short urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	for _, u := range response.Urls {
+		if u.DeletedAt != nil {
+			t := u.DeletedAt.Add(h.cfg.TrashRetention)
+			u.RestorableUntil = &t
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/restore [post]
+// @Summary Restore deleted url
+// @Description Restore your deleted url, possible until the retention window ends
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Url
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) RestoreUrl(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	url, err := h.storage.Url().Restore(int64(id), payload.UserID, time.Now().Add(-h.cfg.TrashRetention))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to restore url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	// the slug may be cached as unknown by the redirect path
+	h.invalidateUrl(url)
+	h.fetchMetadata(url)
+
+	shortUrl, err := h.shortUrl(url)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build short url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseUrlModel(url, shortUrl))
+}
+
+// invalidateUserUrls drops all active urls of the user from the redirect cache
+func (h *handlerV1) invalidateUserUrls(userID int64) error {
+	params := repo.GetAllUrlsParams{
+		Limit:  500,
+		Page:   1,
+		UserID: userID,
+	}
+
+	for {
+		result, err := h.storage.Url().GetAll(&params)
+		if err != nil {
+			return err
+		}
+		if len(result.Urls) > 0 {
+			h.invalidateUrl(result.Urls...)
+		}
+		if len(result.Urls) < int(params.Limit) {
+			return nil
+		}
+		params.Page++
+	}
+}
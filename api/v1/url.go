@@ -5,16 +5,28 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	neturl "net/url"
+	"path"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/SaidovZohid/competition-project/api/models"
-	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/pkg/qr"
+	"github.com/SaidovZohid/competition-project/pkg/slug"
+	"github.com/SaidovZohid/competition-project/pkg/useragent"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
 	"github.com/SaidovZohid/competition-project/storage/repo"
 	"github.com/gin-gonic/gin"
-	_ "github.com/skip2/go-qrcode"
 )
 
+const (
+	QrCodeKey      = "qr_code_"
+	QrCodeCacheTTL = 24 * time.Hour
+)
+
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
 // @Security ApiKeyAuth
 // @Router /urls/make-short-url [post]
 // @Summary Make short url
@@ -22,19 +34,20 @@ import (
 // @Tags url
 // @Accept json
 // @Produce json
-// @Param data query models.CreateShortUrlRequest true "Data"
+// @Param data body models.CreateShortUrlRequest true "Data"
 // @Success 200 {object} models.Url
 // @Failure 500 {object} models.ErrorResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 func (h *handlerV1) MakeShortUrl(ctx *gin.Context) {
 	var (
-		duration time.Duration
-		err      error
+		req models.CreateShortUrlRequest
 	)
-	req, err := validateUrlParams(ctx)
+	// fields come from the body, query strings end up in access logs and
+	// would keep link passwords there
+	err := ctx.ShouldBindJSON(&req)
 	if err != nil {
-		h.logger.WithError(err).Error("failed to validate url params")
+		h.logger.WithError(err).Error("failed to bind json to url")
 		ctx.JSON(http.StatusBadRequest, errorResponse(ErrBadRequest))
 		return
 	}
@@ -44,119 +57,127 @@ func (h *handlerV1) MakeShortUrl(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
 		return
 	}
-	if req.Duration != "" {
-		duration, err = time.ParseDuration(req.Duration)
-		if err != nil {
-			h.logger.WithError(err).Error("failed to parse string to time.Duration")
-			ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
-			return
-		}
+	url, err := h.prepareUrl(&req, payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to prepare url")
+		ctx.JSON(prepareUrlStatus(err), errorResponse(err))
+		return
 	}
-	var shortUrl string
-	if req.CustomUrl == "" {
-		str := utils.RandomString(6)
-		shortUrl = fmt.Sprintf("http://localhost%s/v1/urls/%s", h.cfg.HttpPort, str)
-		err = h.inMemory.Set(shortUrl, req.OriginalUrl, duration)
-		if err != nil {
-			h.logger.WithError(err).Error("failed to set url to redis db")
-			ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
-			return
-		}
-	} else if req.CustomUrl != "" {
-		customUrl := fmt.Sprintf("http://localhost%s/v1/urls/%s", h.cfg.HttpPort, req.CustomUrl)
-		url, err := h.storage.Url().Get(customUrl)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				shortUrl = fmt.Sprintf("http://localhost%s/v1/urls/%s", h.cfg.HttpPort, req.CustomUrl)
-				err = h.inMemory.Set(customUrl, req.OriginalUrl, duration)
-				if err != nil {
-					h.logger.WithError(err).Error("failed to set url to redis db")
-					ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
-					return
-				}
-			}
-		} else if url.HashedUrl == customUrl {
-			h.logger.WithError(err).Error("urls are identical")
-			ctx.JSON(http.StatusBadRequest, errorResponse(ErrUrlUnavailable))
-			return
-		} else {
-			if err != nil {
-				h.logger.WithError(err).Error("failed to get url")
-				ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
-				return
-			}
-		}
+
+	url, err = h.createUrl(url)
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		h.logger.WithError(err).Error("custom url is already taken")
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrUrlUnavailable))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed create url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
 	}
 
-	expiresAt := time.Now().Add(duration)
-	url, err := h.storage.Url().Create(&repo.Url{
-		UserId:      payload.UserID,
-		OriginalUrl: req.OriginalUrl,
-		HashedUrl:   shortUrl,
-		MaxClicks:   &req.MaxClicks,
-		ExpiresAt:   &expiresAt,
-	})
+	shortUrl, err := h.shortUrl(url)
 	if err != nil {
-		h.logger.WithError(err).Error("failed create user")
+		h.logger.WithError(err).Error("failed to build short url")
 		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, parseUrlModel(url))
+	h.cacheUrl(url)
+	h.fetchMetadata(url)
+	h.notify(webhook.EventUrlCreated, url)
+
+	ctx.JSON(http.StatusOK, parseUrlModel(url, shortUrl))
 }
 
 // @Router /urls/{shorturl} [get]
 // @Summary Redirect short url
-// @Description Redirect url by giving short url to original url
+// @Description Redirect url by giving short url to original url.
+// @Description Links of branded domains are served from the root path, e.g. https://go.example.com/{shorturl}
+// @Description Unknown links get 404 and expired or exhausted ones 410, as an html page for browsers.
+// @Description Ended links with a fallback url redirect to it.
+// @Description Crawlers of chat apps get a page with the preview of the link when it has one.
 // @Tags url
 // @Accept json
+// @Produce json
+// @Produce html
 // @Param shorturl path string true "ShortUrl"
 // @Success 302 {object} models.Url
 // @Failure 500 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
 func (h *handlerV1) RedirectUrl(ctx *gin.Context) {
-	url := fmt.Sprintf("http://localhost%s", h.cfg.HttpPort+ctx.Request.URL.Path)
-	url1, err := h.storage.Url().Get(url)
+	slug := path.Base(ctx.Request.URL.Path)
+	domainID, err := h.resolveDomain(ctx.Request.Host)
 	if err != nil {
+		h.logger.WithError(err).Error("failed to resolve domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	url1, passwordKey, err := h.getUrl(slug, domainID)
+	if errors.Is(err, sql.ErrNoRows) {
+		h.urlNotFound(ctx)
+		return
+	} else if err != nil {
 		h.logger.WithError(err).Error("failed to get url")
 		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
 		return
 	}
 
-	if url1.ExpiresAt != nil {
-		if time.Now().After(*url1.ExpiresAt) {
-			h.logger.WithError(err).Error("time expired")
-			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
-			return
-		}
+	now := time.Now()
+	if url1.StartsAt != nil && now.Before(*url1.StartsAt) {
+		h.urlNotFound(ctx)
+		return
+	}
+	if reason := urlEndedReason(url1, now); reason != "" {
+		h.urlGone(ctx, url1, reason)
+		return
+	}
+
+	// crawlers neither unlock the link nor use up its clicks
+	if hasPreview(url1) && useragent.IsPreviewCrawler(ctx.Request.UserAgent()) {
+		h.renderPreviewPage(ctx, url1)
+		return
+	}
+
+	if passwordKey != "" && !h.isUnlocked(ctx, url1.Id, passwordKey) {
+		h.renderPasswordPage(ctx, http.StatusOK, "")
+		return
 	}
+
 	if url1.MaxClicks != nil {
-		if *url1.MaxClicks <= 0 {
-			h.logger.WithError(err).Error("max click is over")
-			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		// the cached counter only filters exhausted links early,
+		// postgres decides whether this click is still allowed
+		remaining, err := h.storage.Url().ConsumeClick(url1.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.invalidateUrl(url1)
+			h.urlGone(ctx, url1, repo.UrlStatusExhausted)
+			return
+		} else if err != nil {
+			h.logger.WithError(err).Error("failed to consume click")
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
 			return
 		}
+		url1.MaxClicks = &remaining
+		h.setCachedUrl(url1, passwordKey)
 	}
-	err = h.storage.Url().DecrementClick(url)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
-		return
-	}
+	target, destinationID := h.redirectTarget(ctx, url1)
+	h.recordClick(ctx, url1, destinationID)
 
-	ctx.Redirect(http.StatusFound, url1.OriginalUrl)
+	ctx.Redirect(http.StatusFound, target)
 }
 
 // @Security ApiKeyAuth
-// @Router /user/{id} [delete]
-// @Summary Delete user by id
-// @Description Delete user by id
-// @Tags user
+// @Router /urls/{id} [delete]
+// @Summary Delete url by id
+// @Description Move url to the trash, it can be restored until the retention window ends
+// @Tags url
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
-// @Param user_id path int true "UserID"
-// @Success 201 {object} models.ResponseOK
+// @Success 200 {object} models.ResponseOK
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 func (h *handlerV1) DeleteUrl(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -164,17 +185,26 @@ func (h *handlerV1) DeleteUrl(c *gin.Context) {
 		return
 	}
 
-	user_id, err := strconv.Atoi(c.Param("user_id"))
+	payload, err := h.GetAuthPayload(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err))
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
 		return
 	}
 
-	err = h.storage.Url().Delete(int64(id), int64(user_id))
+	url, err := h.getOwnUrl(int64(id), payload.UserID)
+	if err != nil {
+		h.ownUrlError(c, err)
+		return
+	}
+
+	err = h.storage.Url().Delete(url.Id, payload.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	h.invalidateUrl(url)
+	h.notify(webhook.EventUrlDeleted, url)
 
 	c.JSON(http.StatusOK, models.ResponseOK{
 		Message: "success",
@@ -182,47 +212,542 @@ func (h *handlerV1) DeleteUrl(c *gin.Context) {
 }
 
 // @Security ApiKeyAuth
-// @Router /urls [put]
+// @Router /urls/{id} [put]
 // @Summary Update a url
-// @Description Update a url
+// @Description Update destination, slug, limits, schedule, password, fallback url, preview, folder or tags of a url,
+// @Description changes of the link itself are kept in its history
 // @Tags url
 // @Accept json
 // @Produce json
+// @Param id path int true "ID"
 // @Param url body models.UpdateUrlRequest true "Url"
 // @Success 201 {object} models.Url
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 func (h *handlerV1) UpdateUrl(c *gin.Context) {
 	var (
 		req models.UpdateUrlRequest
 	)
 
-	err := c.ShouldBindJSON(&req)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	resp, err := h.storage.Url().Update(&repo.Url{
-		HashedUrl: req.HashedUrl,
-		MaxClicks: req.MaxClicks,
-		ExpiresAt: req.ExpiresAt,
-	})
+	err = c.ShouldBindJSON(&req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, parseUrlModel(resp))
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	url, err := h.getOwnUrl(int64(id), payload.UserID)
+	if err != nil {
+		h.ownUrlError(c, err)
+		return
+	}
+
+	if req.HashedUrl == "" {
+		req.HashedUrl = url.HashedUrl
+	} else if req.HashedUrl != url.HashedUrl && !slugPattern.MatchString(req.HashedUrl) {
+		// slugs of older links may not match, they are kept as they are
+		c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidSlug))
+		return
+	}
+	if req.OriginalUrl == "" {
+		req.OriginalUrl = url.OriginalUrl
+	} else if !isHttpUrl(req.OriginalUrl) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidUrl))
+		return
+	}
+	password := url.Password
+	if req.Password != nil {
+		password, err = hashLinkPassword(*req.Password)
+		if err != nil {
+			c.JSON(prepareUrlStatus(err), errorResponse(err))
+			return
+		}
+	}
+	fallbackUrl := url.FallbackUrl
+	if req.FallbackUrl != nil {
+		fallbackUrl = *req.FallbackUrl
+		if fallbackUrl != "" && !isHttpUrl(fallbackUrl) {
+			c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidFallbackUrl))
+			return
+		}
+	}
+	preview := parsePreviewModel(url)
+	if req.Preview != nil {
+		preview = *req.Preview
+		if err := validatePreview(&preview); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	maxClicks := url.MaxClicks
+	if req.MaxClicks.Set {
+		maxClicks = req.MaxClicks.Value
+		if maxClicks != nil && *maxClicks < 0 {
+			c.JSON(http.StatusBadRequest, errorResponse(ErrInvalidMaxClicks))
+			return
+		}
+		// as on create 0 means no limit, only redirects use up clicks
+		if maxClicks != nil && *maxClicks == 0 {
+			maxClicks = nil
+		}
+	}
+	startsAt := url.StartsAt
+	if req.StartsAt.Set {
+		startsAt = req.StartsAt.Value
+	}
+	expiresAt := url.ExpiresAt
+	if req.ExpiresAt.Set {
+		expiresAt = req.ExpiresAt.Value
+	}
+	folderID := url.FolderId
+	if req.FolderId != nil {
+		folderID = *req.FolderId
+	}
+	err = h.checkFolder(folderID, payload.UserID)
+	if err == nil && req.TagIds != nil {
+		err = h.checkTags(*req.TagIds, payload.UserID)
+	}
+	if err != nil {
+		c.JSON(prepareUrlStatus(err), errorResponse(err))
+		return
+	}
+	var tagIDs []int64
+	if req.TagIds != nil {
+		tagIDs = append([]int64{}, *req.TagIds...)
+	}
+	resp, err := h.storage.Url().Update(&repo.Url{
+		Id:          url.Id,
+		UserId:      payload.UserID,
+		OriginalUrl: req.OriginalUrl,
+		HashedUrl:   req.HashedUrl,
+		MaxClicks:   maxClicks,
+		StartsAt:    startsAt,
+		ExpiresAt:   expiresAt,
+		Password:    password,
+		FolderId:    folderID,
+		FallbackUrl: fallbackUrl,
+
+		PreviewTitle:       preview.Title,
+		PreviewDescription: preview.Description,
+		PreviewImage:       preview.Image,
+		TagIds:             tagIDs,
+	}, payload.UserID)
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrUrlUnavailable))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	h.invalidateUrl(url, resp)
+	h.fetchMetadata(resp)
+	h.notify(webhook.EventUrlUpdated, resp)
+
+	shortUrl, err := h.shortUrl(resp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseUrlModel(resp, shortUrl))
 }
 
-func parseUrlModel(data *repo.Url) *models.Url {
+// @Security ApiKeyAuth
+// @Router /urls [get]
+// @Summary Get your urls
+// @Description Get urls of the current user
+// @Tags url
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllUrlsParams false "Filter"
+// @Success 200 {object} models.GetAllUrlsResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetAllUrls(ctx *gin.Context) {
+	params, err := validateGetAllUrlsParams(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to validate get all urls params")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+	params.UserID = payload.UserID
+
+	result, err := h.storage.Url().GetAll(params)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response, err := h.getUrlsResponse(result)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build short urls")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /urls/{id}/qr [get]
+// @Summary Get qr code of url
+// @Description Get qr code of your short url as png or svg
+// @Tags url
+// @Accept json
+// @Produce png
+// @Produce image/svg+xml
+// @Param id path int true "ID"
+// @Param filter query models.GetQrCodeParams false "Filter"
+// @Success 200 {file} binary
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) GetQrCode(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	options, err := validateQrParams(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to validate qr code params")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	url, err := h.getOwnUrl(int64(id), payload.UserID)
+	if err != nil {
+		h.ownUrlError(ctx, err)
+		return
+	}
+	options.Content, err = h.shortUrl(url)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build short url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	// the short url is part of the key, so a changed url never gets a stale code
+	key := fmt.Sprintf("%s%d_%s_%s_%d_%s_%s_%s_%t",
+		QrCodeKey,
+		url.Id,
+		options.Content,
+		options.Format,
+		options.Size,
+		options.Level,
+		options.Foreground,
+		options.Background,
+		options.QuietZone,
+	)
+	cached, err := h.inMemory.Get(key)
+	if err == nil {
+		ctx.Data(http.StatusOK, options.ContentType(), []byte(cached))
+		return
+	}
+
+	image, err := qr.Generate(options)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to generate qr code")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = h.inMemory.Set(key, string(image), QrCodeCacheTTL)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to set qr code to redis db")
+	}
+
+	ctx.Data(http.StatusOK, options.ContentType(), image)
+}
+
+// prepareUrl validates the request and turns it into a url ready to be
+// created, its errors are meant to be shown to the user as they are
+func (h *handlerV1) prepareUrl(req *models.CreateShortUrlRequest, userID int64) (*repo.Url, error) {
+	if !isHttpUrl(req.OriginalUrl) {
+		return nil, ErrInvalidUrl
+	}
+
+	if req.CustomUrl != "" && !slugPattern.MatchString(req.CustomUrl) {
+		return nil, ErrInvalidSlug
+	}
+
+	if req.FallbackUrl != "" && !isHttpUrl(req.FallbackUrl) {
+		return nil, ErrInvalidFallbackUrl
+	}
+
+	if err := validatePreview(&req.Preview); err != nil {
+		return nil, err
+	}
+
+	if req.MaxClicks < 0 {
+		return nil, ErrInvalidMaxClicks
+	}
+
+	var startsAt *time.Time
+	if req.StartsAt != "" {
+		t, err := time.Parse(time.RFC3339, req.StartsAt)
+		if err != nil {
+			return nil, ErrInvalidStartsAt
+		}
+		startsAt = &t
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, ErrInvalidExpiresAt
+		}
+		expiresAt = &t
+	} else if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return nil, ErrInvalidDuration
+		}
+		// the duration of a scheduled link counts from its start
+		t := time.Now()
+		if startsAt != nil && startsAt.After(t) {
+			t = *startsAt
+		}
+		t = t.Add(duration)
+		expiresAt = &t
+	}
+
+	if req.DomainId != 0 {
+		domain, err := h.storage.Domain().Get(req.DomainId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrInvalidDomain
+			}
+			return nil, err
+		}
+		if domain.UserId != userID {
+			return nil, ErrForbidden
+		}
+		if domain.VerifiedAt == nil {
+			return nil, ErrDomainNotVerified
+		}
+	}
+
+	if err := h.checkFolder(req.FolderId, userID); err != nil {
+		return nil, err
+	}
+	if err := h.checkTags(req.TagIds, userID); err != nil {
+		return nil, err
+	}
+
+	password, err := hashLinkPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	maxClicks := req.MaxClicks
+	return &repo.Url{
+		UserId:      userID,
+		OriginalUrl: req.OriginalUrl,
+		HashedUrl:   req.CustomUrl,
+		MaxClicks:   &maxClicks,
+		StartsAt:    startsAt,
+		ExpiresAt:   expiresAt,
+		DomainId:    req.DomainId,
+		FolderId:    req.FolderId,
+		TagIds:      req.TagIds,
+		FallbackUrl: req.FallbackUrl,
+		Password:    password,
+
+		PreviewTitle:       req.Preview.Title,
+		PreviewDescription: req.Preview.Description,
+		PreviewImage:       req.Preview.Image,
+	}, nil
+}
+
+// isHttpUrl reports whether s is an absolute http or https url
+func isHttpUrl(s string) bool {
+	parsed, err := neturl.Parse(s)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func prepareUrlStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidUrl),
+		errors.Is(err, ErrInvalidSlug),
+		errors.Is(err, ErrInvalidMaxClicks),
+		errors.Is(err, ErrInvalidDuration),
+		errors.Is(err, ErrInvalidLinkPassword),
+		errors.Is(err, ErrInvalidStartsAt),
+		errors.Is(err, ErrInvalidExpiresAt),
+		errors.Is(err, ErrInvalidFallbackUrl),
+		errors.Is(err, ErrInvalidPreview),
+		errors.Is(err, ErrInvalidFolder),
+		errors.Is(err, ErrInvalidTag),
+		errors.Is(err, ErrInvalidDomain),
+		errors.Is(err, ErrDomainNotVerified):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// getOwnUrl returns the url only if it belongs to the user
+func (h *handlerV1) getOwnUrl(id, userID int64) (*repo.Url, error) {
+	url, err := h.storage.Url().GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if url.UserId != userID {
+		return nil, ErrForbidden
+	}
+
+	return url, nil
+}
+
+// ownUrlError writes the response for errors returned by getOwnUrl
+func (h *handlerV1) ownUrlError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+	case errors.Is(err, ErrForbidden):
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+	default:
+		h.logger.WithError(err).Error("failed to get url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+	}
+}
+
+// createUrl stores the url with its custom slug, or with a generated one
+// when HashedUrl is empty. Generated slugs are retried on collisions,
+// a taken custom slug is reported with repo.ErrAlreadyExists.
+func (h *handlerV1) createUrl(u *repo.Url) (*repo.Url, error) {
+	if u.HashedUrl != "" {
+		return h.storage.Url().Create(u)
+	}
+
+	for attempt := 0; attempt < slug.MaxAttempts; attempt++ {
+		s, err := h.slugGenerator.Generate(u.OriginalUrl, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		u.HashedUrl = s
+		url, err := h.storage.Url().Create(u)
+		if errors.Is(err, repo.ErrAlreadyExists) {
+			h.logger.WithField("slug", s).Warn("generated slug is already taken")
+			continue
+		}
+		return url, err
+	}
+
+	return nil, fmt.Errorf("failed to generate a free slug in %d attempts", slug.MaxAttempts)
+}
+
+// fetchMetadata wakes up the metadata fetcher when the page of the url
+// was not fetched yet, it does not wait for the fetch
+func (h *handlerV1) fetchMetadata(url *repo.Url) {
+	if url.MetaFetchedAt == nil {
+		h.metadataFetcher.Wake()
+	}
+}
+
+// shortUrl builds the public address of the url, links of branded
+// domains use the scheme of PUBLIC_BASE_URL with their own host
+func (h *handlerV1) shortUrl(url *repo.Url) (string, error) {
+	if url.DomainId == 0 {
+		return slug.Url(h.cfg.PublicBaseUrl, "", url.HashedUrl), nil
+	}
+
+	domain, err := h.storage.Domain().Get(url.DomainId)
+	if err != nil {
+		return "", err
+	}
+
+	return slug.Url(h.cfg.PublicBaseUrl, domain.Host, url.HashedUrl), nil
+}
+
+func (h *handlerV1) getUrlsResponse(data *repo.GetAllUrlsResult) (*models.GetAllUrlsResponse, error) {
+	response := models.GetAllUrlsResponse{
+		Urls:  make([]*models.Url, 0),
+		Count: data.Count,
+	}
+
+	for _, url := range data.Urls {
+		shortUrl, err := h.shortUrl(url)
+		if err != nil {
+			return nil, err
+		}
+		response.Urls = append(response.Urls, parseUrlModel(url, shortUrl))
+	}
+
+	return &response, nil
+}
+
+func parseUrlModel(data *repo.Url, shortUrl string) *models.Url {
 	return &models.Url{
-		Id:          data.Id,
-		UserId:      data.UserId,
-		OriginalUrl: data.OriginalUrl,
-		HashedUrl:   data.HashedUrl,
-		MaxClicks:   data.MaxClicks,
-		ExpiresAt:   data.ExpiresAt,
-		CreatedAt:   data.CreatedAt.Format(time.RFC3339),
-	}
-}
\ No newline at end of file
+		Id:             data.Id,
+		UserId:         data.UserId,
+		OriginalUrl:    data.OriginalUrl,
+		HashedUrl:      data.HashedUrl,
+		ShortUrl:       shortUrl,
+		DomainId:       data.DomainId,
+		FolderId:       data.FolderId,
+		TagIds:         data.TagIds,
+		MaxClicks:      data.MaxClicks,
+		StartsAt:       data.StartsAt,
+		ExpiresAt:      data.ExpiresAt,
+		Active:         data.Active,
+		InactiveReason: data.InactiveReason,
+		FallbackUrl:    data.FallbackUrl,
+		Preview:        parsePreviewModel(data),
+		Metadata:       parseMetadataModel(data),
+		Clicks:         data.Clicks,
+		Protected:      data.Password != "",
+		DeletedAt:      data.DeletedAt,
+		CreatedAt:      data.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// parseMetadataModel returns nil until the page of the url was fetched
+func parseMetadataModel(url *repo.Url) *models.UrlMetadata {
+	if url.MetaFetchedAt == nil {
+		return nil
+	}
+	return &models.UrlMetadata{
+		Title:        url.MetaTitle,
+		Description:  url.MetaDescription,
+		CanonicalUrl: url.CanonicalUrl,
+		FaviconUrl:   url.FaviconUrl,
+		FetchedAt:    *url.MetaFetchedAt,
+	}
+}
@@ -0,0 +1,182 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+)
+
+const (
+	DomainCacheKey = "domain_host_"
+	UrlCacheTTL    = time.Hour
+)
+
+// cachedUrl has the fields of repo.Url the redirect path reads. The
+// password hash stays in postgres, PasswordKey only tells whether the url
+// is protected and which password its unlock cookies were given for.
+type cachedUrl struct {
+	Id                 int64      `json:"id"`
+	UserId             int64      `json:"user_id"`
+	OriginalUrl        string     `json:"original_url"`
+	HashedUrl          string     `json:"hashed_url"`
+	DomainId           int64      `json:"domain_id"`
+	MaxClicks          *int64     `json:"max_clicks"`
+	StartsAt           *time.Time `json:"starts_at"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+	Active             bool       `json:"active"`
+	InactiveReason     string     `json:"inactive_reason"`
+	StickyDestinations bool       `json:"sticky_destinations"`
+	FallbackUrl        string     `json:"fallback_url"`
+	PreviewTitle       string     `json:"preview_title"`
+	PreviewDescription string     `json:"preview_description"`
+	PreviewImage       string     `json:"preview_image"`
+	PasswordKey        string     `json:"password_key"`
+}
+
+// passwordKey identifies the password hash of a url without revealing it,
+// it is empty for urls without a password
+func passwordKey(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:])
+}
+
+// getUrl is the read-through cache of the redirect path. The active flag
+// and click limits are checked without postgres, the returned url has only
+// the cached fields and no password hash.
+func (h *handlerV1) getUrl(slug string, domainID int64) (*repo.Url, string, error) {
+	data, err := h.inMemory.Get(storage.UrlCacheKey(domainID, slug))
+	if err == nil {
+		var cached cachedUrl
+		if err := json.Unmarshal([]byte(data), &cached); err == nil {
+			return cached.url(), cached.PasswordKey, nil
+		}
+		h.logger.WithError(err).Error("failed to unmarshal cached url")
+	}
+
+	url, err := h.storage.Url().Get(slug, domainID)
+	if err != nil {
+		return nil, "", err
+	}
+	key := passwordKey(url.Password)
+	h.cacheUrl(url)
+
+	url.Password = ""
+	return url, key, nil
+}
+
+// cacheUrl stores the url until it expires, but not longer than UrlCacheTTL
+func (h *handlerV1) cacheUrl(url *repo.Url) {
+	h.setCachedUrl(url, passwordKey(url.Password))
+}
+
+// setCachedUrl stores a url returned by getUrl, which has no password hash
+// to take the key from
+func (h *handlerV1) setCachedUrl(url *repo.Url, passwordKey string) {
+	ttl := UrlCacheTTL
+	if url.ExpiresAt != nil {
+		if left := time.Until(*url.ExpiresAt); left < ttl {
+			ttl = left
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(&cachedUrl{
+		Id:                 url.Id,
+		UserId:             url.UserId,
+		OriginalUrl:        url.OriginalUrl,
+		HashedUrl:          url.HashedUrl,
+		DomainId:           url.DomainId,
+		MaxClicks:          url.MaxClicks,
+		StartsAt:           url.StartsAt,
+		ExpiresAt:          url.ExpiresAt,
+		Active:             url.Active,
+		InactiveReason:     url.InactiveReason,
+		StickyDestinations: url.StickyDestinations,
+		FallbackUrl:        url.FallbackUrl,
+		PreviewTitle:       url.PreviewTitle,
+		PreviewDescription: url.PreviewDescription,
+		PreviewImage:       url.PreviewImage,
+		PasswordKey:        passwordKey,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to marshal url")
+		return
+	}
+
+	err = h.inMemory.Set(storage.UrlCacheKey(url.DomainId, url.HashedUrl), string(data), ttl)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to set url to redis db")
+	}
+}
+
+func (c *cachedUrl) url() *repo.Url {
+	return &repo.Url{
+		Id:                 c.Id,
+		UserId:             c.UserId,
+		OriginalUrl:        c.OriginalUrl,
+		HashedUrl:          c.HashedUrl,
+		DomainId:           c.DomainId,
+		MaxClicks:          c.MaxClicks,
+		StartsAt:           c.StartsAt,
+		ExpiresAt:          c.ExpiresAt,
+		Active:             c.Active,
+		InactiveReason:     c.InactiveReason,
+		StickyDestinations: c.StickyDestinations,
+		FallbackUrl:        c.FallbackUrl,
+		PreviewTitle:       c.PreviewTitle,
+		PreviewDescription: c.PreviewDescription,
+		PreviewImage:       c.PreviewImage,
+	}
+}
+
+func (h *handlerV1) invalidateUrl(urls ...*repo.Url) {
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		keys = append(keys, storage.UrlCacheKey(url.DomainId, url.HashedUrl))
+	}
+
+	err := h.inMemory.Del(keys...)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to delete url from redis db")
+	}
+}
+
+// resolveDomain returns id of the branded domain the request came to,
+// 0 means the request came to the default PUBLIC_BASE_URL host
+func (h *handlerV1) resolveDomain(host string) (int64, error) {
+	host = normalizeHost(host)
+	key := DomainCacheKey + host
+
+	if data, err := h.inMemory.Get(key); err == nil {
+		if id, err := strconv.ParseInt(data, 10, 64); err == nil {
+			return id, nil
+		}
+	}
+
+	var id int64
+	domain, err := h.storage.Domain().GetByHost(host)
+	if err == nil {
+		id = domain.Id
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	err = h.inMemory.Set(key, strconv.FormatInt(id, 10), UrlCacheTTL)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to set domain to redis db")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	notFoundPageTemplate = "./templates/url_not_found.html"
+	gonePageTemplate     = "./templates/url_gone.html"
+	previewPageTemplate  = "./templates/url_preview.html"
+
+	MaxPreviewTitleLength       = 200
+	MaxPreviewDescriptionLength = 500
+)
+
+// urlNotFound answers visitors of unknown links and links which have not
+// started yet
+func (h *handlerV1) urlNotFound(ctx *gin.Context) {
+	h.renderUrlPage(ctx, http.StatusNotFound, notFoundPageTemplate, ErrNotFound, "")
+}
+
+// urlGone sends visitors of an ended link to its fallback url, or tells
+// them the link is gone
+func (h *handlerV1) urlGone(ctx *gin.Context, url *repo.Url, reason string) {
+	if url.FallbackUrl != "" {
+		ctx.Redirect(http.StatusFound, url.FallbackUrl)
+		return
+	}
+	h.renderUrlPage(ctx, http.StatusGone, gonePageTemplate, ErrGone, reason)
+}
+
+// renderUrlPage shows the page to browsers, API clients which do not ask
+// for html get the error as json
+func (h *handlerV1) renderUrlPage(ctx *gin.Context, status int, page string, err error, reason string) {
+	if ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		ctx.JSON(status, errorResponse(err))
+		return
+	}
+
+	h.renderPage(ctx, status, page, map[string]string{
+		"status": strconv.Itoa(status) + " " + http.StatusText(status),
+		"reason": reason,
+	})
+}
+
+// hasPreview reports whether the owner set any preview of the url
+func hasPreview(url *repo.Url) bool {
+	return url.PreviewTitle != "" || url.PreviewDescription != "" || url.PreviewImage != ""
+}
+
+// renderPreviewPage answers crawlers of chat apps with the preview of the
+// url instead of redirecting them to the destination
+func (h *handlerV1) renderPreviewPage(ctx *gin.Context, url *repo.Url) {
+	shortUrl, err := h.shortUrl(url)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to build short url")
+	}
+
+	card := "summary"
+	if url.PreviewImage != "" {
+		card = "summary_large_image"
+	}
+
+	h.renderPage(ctx, http.StatusOK, previewPageTemplate, map[string]string{
+		"url":         shortUrl,
+		"title":       url.PreviewTitle,
+		"description": url.PreviewDescription,
+		"image":       url.PreviewImage,
+		"card":        card,
+	})
+}
+
+// validatePreview trims the preview and checks its lengths and image url
+func validatePreview(p *models.UrlPreview) error {
+	p.Title = strings.TrimSpace(p.Title)
+	p.Description = strings.TrimSpace(p.Description)
+	p.Image = strings.TrimSpace(p.Image)
+
+	if utf8.RuneCountInString(p.Title) > MaxPreviewTitleLength ||
+		utf8.RuneCountInString(p.Description) > MaxPreviewDescriptionLength ||
+		(p.Image != "" && !isHttpUrl(p.Image)) {
+		return ErrInvalidPreview
+	}
+	return nil
+}
+
+func parsePreviewModel(url *repo.Url) models.UrlPreview {
+	return models.UrlPreview{
+		Title:       url.PreviewTitle,
+		Description: url.PreviewDescription,
+		Image:       url.PreviewImage,
+	}
+}
+
+func (h *handlerV1) renderPage(ctx *gin.Context, status int, page string, data map[string]string) {
+	t, err := template.ParseFiles(page)
+	if err != nil {
+		h.logger.WithError(err).WithField("page", page).Error("failed to parse page template")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(status)
+	ctx.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	err = t.Execute(ctx.Writer, data)
+	if err != nil {
+		h.logger.WithError(err).WithField("page", page).Error("failed to render page")
+	}
+}
+
+// urlEndedReason tells whether the url expired or ran out of clicks,
+// it does not wait for the sweeper to deactivate the url
+func urlEndedReason(url *repo.Url, now time.Time) string {
+	switch {
+	case !url.Active && url.InactiveReason != "":
+		return url.InactiveReason
+	case !url.Active, url.ExpiresAt != nil && !url.ExpiresAt.After(now):
+		return repo.UrlStatusExpired
+	case url.MaxClicks != nil && *url.MaxClicks <= 0:
+		return repo.UrlStatusExhausted
+	}
+	return ""
+}
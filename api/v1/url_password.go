@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	UnlockCookiePrefix = "url_unlock_"
+	UnlockKey          = "url_unlock_"
+	UnlockAttemptsKey  = "url_unlock_attempts_"
+	UnlockTTL          = 30 * time.Minute
+
+	// visitors get UnlockMaxAttempts wrong passwords per UnlockAttemptsWindow
+	UnlockMaxAttempts    = 5
+	UnlockAttemptsWindow = 15 * time.Minute
+	// UnlockLinkMaxFailures caps wrong passwords of a link from all visitors
+	// together, so changing ips does not allow guessing
+	UnlockLinkMaxFailures = 100
+
+	passwordPageTemplate = "./templates/url_password.html"
+)
+
+// @Router /urls/{shorturl} [post]
+// @Summary Unlock password protected url
+// @Description Checks the password sent from the unlock page and redirects back to the short url.
+// @Description Links of branded domains are unlocked from the root path, e.g. https://go.example.com/{shorturl}
+// @Tags url
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param shorturl path string true "ShortUrl"
+// @Param password formData string true "Password"
+// @Success 303
+// @Failure 401
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 429
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) UnlockUrl(ctx *gin.Context) {
+	slug := path.Base(ctx.Request.URL.Path)
+	domainID, err := h.resolveDomain(ctx.Request.Host)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to resolve domain")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	// the password hash is not cached, it is read from postgres
+	url, err := h.storage.Url().Get(slug, domainID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.urlNotFound(ctx)
+			return
+		}
+		h.logger.WithError(err).Error("failed to get url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	if url.Password == "" || h.isUnlocked(ctx, url.Id, passwordKey(url.Password)) {
+		ctx.Redirect(http.StatusSeeOther, ctx.Request.URL.Path)
+		return
+	}
+
+	// only failures count against the link, visitors who know the
+	// password must not lock it for the others
+	failuresKey := UnlockAttemptsKey + strconv.FormatInt(url.Id, 10)
+	if data, err := h.inMemory.Get(failuresKey); err == nil {
+		if failures, _ := strconv.Atoi(data); failures >= UnlockLinkMaxFailures {
+			h.renderPasswordPage(ctx, http.StatusTooManyRequests, "Too many attempts, please try again later.")
+			return
+		}
+	}
+
+	attemptsKey := failuresKey + "_" + h.hashIP(ctx.ClientIP())
+	attempts, err := h.inMemory.Incr(attemptsKey, UnlockAttemptsWindow)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to count unlock attempts")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	if attempts > UnlockMaxAttempts {
+		h.renderPasswordPage(ctx, http.StatusTooManyRequests, "Too many attempts, please try again later.")
+		return
+	}
+
+	err = utils.CheckPassword(ctx.PostForm("password"), url.Password)
+	if err != nil {
+		_, err = h.inMemory.Incr(failuresKey, UnlockAttemptsWindow)
+		if err != nil {
+			h.logger.WithError(err).Error("failed to count unlock failures")
+		}
+		h.renderPasswordPage(ctx, http.StatusUnauthorized, "Wrong password, please try again.")
+		return
+	}
+
+	err = h.unlock(ctx, url)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to unlock url")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = h.inMemory.Del(attemptsKey)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to delete unlock attempts from redis db")
+	}
+
+	ctx.Redirect(http.StatusSeeOther, ctx.Request.URL.Path)
+}
+
+// unlock gives the visitor a cookie with a random token. The token is kept
+// with the passwordKey of the url, so changing the password locks the link
+// again.
+func (h *handlerV1) unlock(ctx *gin.Context, url *repo.Url) error {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return err
+	}
+	token := hex.EncodeToString(b)
+
+	err = h.inMemory.Set(UnlockKey+token, passwordKey(url.Password), UnlockTTL)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(
+		UnlockCookiePrefix+strconv.FormatInt(url.Id, 10),
+		token,
+		int(UnlockTTL.Seconds()),
+		"/",
+		"",
+		ctx.Request.TLS != nil,
+		true,
+	)
+
+	return nil
+}
+
+func (h *handlerV1) isUnlocked(ctx *gin.Context, urlID int64, passwordKey string) bool {
+	token, err := ctx.Cookie(UnlockCookiePrefix + strconv.FormatInt(urlID, 10))
+	if err != nil || token == "" {
+		return false
+	}
+
+	key, err := h.inMemory.Get(UnlockKey + token)
+	if err != nil {
+		return false
+	}
+
+	return key == passwordKey
+}
+
+func (h *handlerV1) renderPasswordPage(ctx *gin.Context, status int, message string) {
+	h.renderPage(ctx, status, passwordPageTemplate, map[string]string{
+		"action": ctx.Request.URL.Path,
+		"error":  message,
+	})
+}
+
+// hashLinkPassword returns the hash to store for the link password,
+// an empty password means the link is not protected
+func hashLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	// bcrypt ignores everything after 72 bytes
+	if len(password) < 4 || len(password) > 72 {
+		return "", ErrInvalidLinkPassword
+	}
+
+	return utils.HashPassword(password)
+}
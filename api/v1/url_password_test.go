@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnlockUrl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	strg := newFakeStorage()
+	inMemory := newFakeInMemory()
+	h := newTestHandler(strg, inMemory)
+
+	router := gin.New()
+	router.POST("/:shorturl", h.UnlockUrl)
+
+	hash, err := utils.HashPassword("secret")
+	require.NoError(t, err)
+	strg.urls.urls["locked"] = &repo.Url{Id: 3, UserId: 1, OriginalUrl: "https://example.com", HashedUrl: "locked", Active: true, Password: hash}
+
+	unlock := func(ip, password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		req := httptest.NewRequest(http.MethodPost, "/locked", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < UnlockMaxAttempts; i++ {
+		require.Equal(t, http.StatusUnauthorized, unlock("198.51.100.1", "wrong").Code)
+	}
+	require.Equal(t, http.StatusTooManyRequests, unlock("198.51.100.1", "secret").Code)
+
+	// other visitors are not limited by the ip
+	w := unlock("198.51.100.2", "secret")
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	require.Len(t, w.Result().Cookies(), 1)
+
+	// but by the failures of the link
+	require.NoError(t, inMemory.Set(UnlockAttemptsKey+"3", strconv.Itoa(UnlockLinkMaxFailures), UnlockAttemptsWindow))
+	require.Equal(t, http.StatusTooManyRequests, unlock("198.51.100.3", "secret").Code)
+}
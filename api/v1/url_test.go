@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectUrl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	strg := newFakeStorage()
+	inMemory := newFakeInMemory()
+	h := newTestHandler(strg, inMemory)
+
+	router := gin.New()
+	router.GET("/:shorturl", h.RedirectUrl)
+
+	get := func(slug string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+slug, nil)
+		req.Header.Set("Accept", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	maxClicks := int64(1)
+	hash, err := utils.HashPassword("secret")
+	require.NoError(t, err)
+	strg.urls.urls["abc"] = &repo.Url{Id: 1, UserId: 1, OriginalUrl: "https://example.com/a", HashedUrl: "abc", Active: true}
+	strg.urls.urls["once"] = &repo.Url{Id: 2, UserId: 1, OriginalUrl: "https://example.com/b", HashedUrl: "once", Active: true, MaxClicks: &maxClicks}
+	strg.urls.urls["locked"] = &repo.Url{Id: 3, UserId: 1, OriginalUrl: "https://example.com/c", HashedUrl: "locked", Active: true, Password: hash}
+
+	w := get("abc")
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "https://example.com/a", w.Header().Get("Location"))
+
+	select {
+	case click := <-strg.clicks.created:
+		require.Equal(t, int64(1), click.UrlId)
+		require.NotEmpty(t, click.IpHash)
+	case <-time.After(time.Second):
+		t.Fatal("click was not recorded")
+	}
+
+	// the second redirect is served from the cache
+	w = get("abc")
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, 1, strg.urls.gets)
+	<-strg.clicks.created
+
+	w = get("missing")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// the last click of a limited url is used up once
+	w = get("once")
+	require.Equal(t, http.StatusFound, w.Code)
+	<-strg.clicks.created
+	w = get("once")
+	require.Equal(t, http.StatusGone, w.Code)
+
+	// password hashes are not cached
+	w = get("locked")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "password")
+	cached, err := inMemory.Get(storage.UrlCacheKey(0, "locked"))
+	require.NoError(t, err)
+	require.False(t, strings.Contains(cached, hash))
+
+	// unlocked visitors are redirected
+	require.NoError(t, inMemory.Set(UnlockKey+"token", passwordKey(hash), UnlockTTL))
+	w = get("locked", &http.Cookie{Name: "url_unlock_3", Value: "token"})
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "https://example.com/c", w.Header().Get("Location"))
+	<-strg.clicks.created
+}
@@ -1,10 +1,14 @@
 package v1
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/utils"
 	"github.com/SaidovZohid/competition-project/storage/repo"
 	"github.com/gin-gonic/gin"
 )
@@ -89,13 +93,15 @@ func (h *handlerV1) GetUserByEmail(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Router /users/{id} [delete]
 // @Summary Delete user by id
-// @Description Delete user by id
+// @Description Move your account and your links to the trash, they can be restored until the retention window ends
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
 // @Success 201 {object} models.ResponseOK
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 func (h *handlerV1) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -103,12 +109,102 @@ func (h *handlerV1) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+	if payload.UserID != int64(id) {
+		c.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	// cached links would keep redirecting until their entries expire
+	err = h.invalidateUserUrls(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to invalidate urls of user")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
 	err = h.storage.User().Delete(int64(id))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	// a deleted account must not stay logged in anywhere
+	err = h.revokeSessions(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to revoke sessions")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// @Router /users/restore [post]
+// @Summary Restore deleted user
+// @Description Restore your deleted account and the links deleted with it, possible until the retention window ends
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.LoginRequest true "Data"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+func (h *handlerV1) RestoreUser(c *gin.Context) {
+	var (
+		req models.LoginRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	since := time.Now().Add(-h.cfg.TrashRetention)
+	user, err := h.storage.User().GetDeletedByEmail(req.Email, since)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+			return
+		}
+		h.logger.WithError(err).Error("failed to get deleted user by email")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = utils.CheckPassword(req.Password, user.Password)
+	if err != nil {
+		c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+		return
+	}
+
+	err = h.storage.User().Restore(user.Id, since)
+	if errors.Is(err, repo.ErrAlreadyExists) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+		return
+	} else if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to restore user")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
 	c.JSON(http.StatusOK, models.ResponseOK{
 		Message: "success",
 	})
@@ -147,6 +243,89 @@ func (h *handlerV1) UpdateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, parseUserModel(resp))
 }
 
+// @Security ApiKeyAuth
+// @Router /users/password [put]
+// @Summary Change password
+// @Description Change your password. Every session is revoked, so tokens issued before stop working,
+// @Description new tokens for the current device are returned.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.ChangePasswordRequest true "Data"
+// @Success 200 {object} models.TokensResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+func (h *handlerV1) ChangePassword(c *gin.Context) {
+	var (
+		req models.ChangePasswordRequest
+	)
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(c)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		c.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	if !validatePassword(req.NewPassword) {
+		c.JSON(http.StatusBadRequest, errorResponse(ErrWeakPassword))
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(payload.Email)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.Id != payload.UserID) {
+		c.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to get user by email")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = utils.CheckPassword(req.OldPassword, user.Password)
+	if err != nil {
+		c.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to hash password")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = h.storage.User().UpdatePassword(user.Id, hashedPassword)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to update password")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	err = h.revokeSessions(user.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to revoke sessions")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	tokens, err := h.startSession(user.Id, user.Email)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to create token")
+		c.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
 func getUsersResponse(data *repo.GetAllUsersResult) *models.GetAllUsersResponse {
 	response := models.GetAllUsersResponse{
 		Users: make([]*models.User, 0),
@@ -0,0 +1,462 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/SaidovZohid/competition-project/api/models"
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/gin-gonic/gin"
+)
+
+// @Security ApiKeyAuth
+// @Router /webhooks [post]
+// @Summary Register a webhook
+// @Description Register an endpoint which gets a POST for every subscribed event of your urls,
+// @Description events are url.created, url.updated, url.deleted, url.expired, url.exhausted and url.clicks.
+// @Description Every delivery is signed: X-Webhook-Signature is sha256= and the hex HMAC-SHA256 of
+// @Description "{X-Webhook-Timestamp}.{body}" keyed with the secret, which is returned only by this request.
+// @Description Deliveries which do not get a 2xx answer are retried with a doubling delay.
+// @Description Endpoints on loopback, private or link-local addresses are refused.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param data body models.CreateWebhookRequest true "Data"
+// @Success 201 {object} models.Webhook
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) CreateWebhook(ctx *gin.Context) {
+	var (
+		req models.CreateWebhookRequest
+	)
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to bind json to webhook")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	events, err := validateWebhook(ctx.Request.Context(), req.Url, req.Events)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		h.logger.WithError(err).Error("failed to generate webhook secret")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	result, err := h.storage.Webhook().Create(&repo.Webhook{
+		UserId: payload.UserID,
+		Url:    req.Url,
+		Secret: secret,
+		Events: events,
+		Active: true,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to create webhook")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := parseWebhookModel(result)
+	response.Secret = result.Secret
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /webhooks [get]
+// @Summary Get your webhooks
+// @Description Get all webhooks of the current user
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.GetAllWebhooksResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+func (h *handlerV1) GetAllWebhooks(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	webhooks, err := h.storage.Webhook().GetAll(payload.UserID)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get webhooks")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetAllWebhooksResponse{
+		Webhooks: make([]*models.Webhook, 0),
+		Count:    int32(len(webhooks)),
+	}
+	for _, w := range webhooks {
+		response.Webhooks = append(response.Webhooks, parseWebhookModel(w))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [put]
+// @Summary Update a webhook
+// @Description Change the endpoint or the events of a webhook, or disable it. The secret is kept.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.UpdateWebhookRequest true "Data"
+// @Success 200 {object} models.Webhook
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) UpdateWebhook(ctx *gin.Context) {
+	var (
+		req models.UpdateWebhookRequest
+	)
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	events, err := validateWebhook(ctx.Request.Context(), req.Url, req.Events)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	w, err := h.getOwnWebhook(int64(id), payload.UserID)
+	if err != nil {
+		h.ownWebhookError(ctx, err)
+		return
+	}
+
+	active := w.Active
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	result, err := h.storage.Webhook().Update(&repo.Webhook{
+		Id:     w.Id,
+		UserId: payload.UserID,
+		Url:    req.Url,
+		Events: events,
+		Active: active,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to update webhook")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	// queued deliveries of a webhook which is enabled again are due now
+	if active {
+		h.webhookSender.Wake()
+	}
+
+	ctx.JSON(http.StatusOK, parseWebhookModel(result))
+}
+
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [delete]
+// @Summary Delete webhook by id
+// @Description Delete webhook by id, its queued deliveries are dropped
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.ResponseOK
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) DeleteWebhook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	err = h.storage.Webhook().Delete(int64(id), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+			return
+		}
+		h.logger.WithError(err).Error("failed to delete webhook")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.ResponseOK{
+		Message: "success",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /webhooks/{id}/deliveries [get]
+// @Summary Get deliveries of a webhook
+// @Description Get the delivery log of a webhook with the result of the last attempt of every delivery, newest first
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param filter query models.GetAllParams false "Filter"
+// @Success 200 {object} models.GetWebhookDeliveriesResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) GetWebhookDeliveries(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	params, err := validateGetAllParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	w, err := h.getOwnWebhook(int64(id), payload.UserID)
+	if err != nil {
+		h.ownWebhookError(ctx, err)
+		return
+	}
+
+	result, err := h.storage.Webhook().GetDeliveries(&repo.GetWebhookDeliveriesParams{
+		Limit:     params.Limit,
+		Page:      params.Page,
+		WebhookID: w.Id,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get webhook deliveries")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	response := models.GetWebhookDeliveriesResponse{
+		Deliveries: make([]*models.WebhookDelivery, 0),
+		Count:      result.Count,
+	}
+	for _, d := range result.Deliveries {
+		response.Deliveries = append(response.Deliveries, parseDeliveryModel(d))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+// @Summary Redeliver a webhook delivery
+// @Description Queue a copy of the delivery with the same payload, it is sent right away
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 201 {object} models.WebhookDelivery
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+func (h *handlerV1) RedeliverWebhook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(ctx.Param("delivery_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to get authorization payload")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrUnauthorized))
+		return
+	}
+
+	w, err := h.getOwnWebhook(int64(id), payload.UserID)
+	if err != nil {
+		h.ownWebhookError(ctx, err)
+		return
+	}
+
+	delivery, err := h.storage.Webhook().GetDelivery(int64(deliveryID))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && delivery.WebhookId != w.Id) {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+		return
+	} else if err != nil {
+		h.logger.WithError(err).Error("failed to get webhook delivery")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+
+	result, err := h.storage.Webhook().Redeliver(delivery.Id)
+	if err != nil {
+		h.logger.WithError(err).Error("failed to redeliver webhook")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		return
+	}
+	h.webhookSender.Wake()
+
+	ctx.JSON(http.StatusCreated, parseDeliveryModel(result))
+}
+
+// notify queues the event of the url for the webhooks of its owner in the
+// background, so requests never wait for it
+func (h *handlerV1) notify(event string, url *repo.Url) {
+	go func() {
+		err := h.webhookSender.Notify(event, url)
+		if err != nil {
+			h.logger.WithError(err).WithField("url_id", url.Id).Error("failed to notify webhooks")
+		}
+	}()
+}
+
+// validateWebhook checks the endpoint and returns the events without
+// duplicates. Endpoints on the internal network are refused, the sender
+// checks the address again whenever it connects.
+func validateWebhook(ctx context.Context, rawUrl string, events []string) ([]string, error) {
+	if !isHttpUrl(rawUrl) {
+		return nil, ErrInvalidUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, ErrInvalidUrl
+	}
+	err = netguard.CheckHost(ctx, u.Hostname())
+	if errors.Is(err, netguard.ErrForbiddenAddress) {
+		return nil, ErrPrivateUrl
+	} else if err != nil {
+		return nil, ErrInvalidUrl
+	}
+
+	if len(events) == 0 {
+		return nil, ErrInvalidEvent
+	}
+
+	result := make([]string, 0, len(events))
+	seen := make(map[string]bool)
+	for _, e := range events {
+		if !webhook.IsEvent(e) {
+			return nil, ErrInvalidEvent
+		}
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+// getOwnWebhook returns the webhook only if it belongs to the user
+func (h *handlerV1) getOwnWebhook(id, userID int64) (*repo.Webhook, error) {
+	w, err := h.storage.Webhook().Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if w.UserId != userID {
+		return nil, ErrForbidden
+	}
+
+	return w, nil
+}
+
+// ownWebhookError writes the response for errors returned by getOwnWebhook
+func (h *handlerV1) ownWebhookError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrNotFound))
+	case errors.Is(err, ErrForbidden):
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+	default:
+		h.logger.WithError(err).Error("failed to get webhook")
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+	}
+}
+
+func parseWebhookModel(w *repo.Webhook) *models.Webhook {
+	return &models.Webhook{
+		Id:        w.Id,
+		UserId:    w.UserId,
+		Url:       w.Url,
+		Events:    w.Events,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func parseDeliveryModel(d *repo.WebhookDelivery) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		Id:            d.Id,
+		WebhookId:     d.WebhookId,
+		Event:         d.Event,
+		Payload:       d.Payload,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		StatusCode:    d.StatusCode,
+		Error:         d.Error,
+		LastAttemptAt: d.LastAttemptAt,
+		DeliveredAt:   d.DeliveredAt,
+		CreatedAt:     d.CreatedAt,
+	}
+}
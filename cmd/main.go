@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	_ "time/tzdata" // rule timezones have to load on images without tzdata
 
 	"github.com/SaidovZohid/competition-project/api"
 	"github.com/SaidovZohid/competition-project/config"
 	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/SaidovZohid/competition-project/pkg/slug"
 	"github.com/SaidovZohid/competition-project/pkg/token"
 	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/worker"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
@@ -19,6 +24,10 @@ func main() {
 	logger.Init()
 	log := logger.GetLogger()
 
+	if err := cfg.Validate(); err != nil {
+		log.WithError(err).Fatal("invalid config")
+	}
+
 	psqlUrl := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Postgres.Host,
 		cfg.Postgres.Port,
@@ -39,16 +48,40 @@ func main() {
 	strg := storage.NewStoragePg(psqlConn)
 	inMemory := storage.NewInMemoryStorage(rdb)
 
-	tMaker, err := token.NewJWTMaker(cfg.AuthSecretKey)
+	tMaker, err := token.NewMaker(cfg.TokenType, cfg.AuthSecretKey, cfg.TokenPrivateKey)
+	if err != nil {
+		log.WithError(err).Fatal("error while making token maker")
+	}
+
+	slugGenerator, err := slug.NewGenerator(cfg.Slug.Strategy, cfg.Slug.Length, strg.Url().NextSlugSequence)
 	if err != nil {
-		log.WithError(err).Fatal("error while making token JWT maker")
+		log.WithError(err).Fatal("error while making slug generator")
 	}
+
+	webhookSender := worker.NewWebhookSender(strg, &log, cfg.Webhook, cfg.PublicBaseUrl, netguard.NewDialer())
+	go webhookSender.Run(context.Background())
+
+	sweeper := worker.NewSweeper(strg, inMemory, webhookSender, &log, cfg.SweeperInterval)
+	go sweeper.Run(context.Background())
+
+	purger := worker.NewPurger(strg, &log, cfg.TrashRetention)
+	go purger.Run(context.Background())
+
+	healthChecker := worker.NewHealthChecker(strg, &log, cfg.HealthCheck, netguard.NewDialer())
+	go healthChecker.Run(context.Background())
+
+	metadataFetcher := worker.NewMetadataFetcher(strg, &log, cfg.Metadata, netguard.NewDialer())
+	go metadataFetcher.Run(context.Background())
+
 	api := api.New(&api.RouterOptions{
-		Cfg:        &cfg,
-		Storage:    strg,
-		InMemory:   inMemory,
-		TokenMaker: tMaker,
-		Logger:     &log,
+		Cfg:             &cfg,
+		Storage:         strg,
+		InMemory:        inMemory,
+		TokenMaker:      tMaker,
+		SlugGenerator:   slugGenerator,
+		Logger:          &log,
+		MetadataFetcher: metadataFetcher,
+		WebhookSender:   webhookSender,
 	})
 
 	if err := api.Run(cfg.HttpPort); err != nil {
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -9,13 +11,34 @@ import (
 
 type Config struct {
 	HttpPort            string
+	PublicBaseUrl       string
 	Postgres            PostgresConfig
 	Smtp                Smtp
+	Slug                Slug
 	RedisAddr           string
 	AuthSecretKey       string
 	AuthHeaderKey       string
 	AuthPayloadKey      string
 	AccessTokenDuration time.Duration
+	// RefreshTokenDuration is how long a session lasts without being
+	// refreshed
+	RefreshTokenDuration time.Duration
+	SweeperInterval      time.Duration
+	TrashRetention       time.Duration
+	HealthCheck          HealthCheck
+	Metadata             Metadata
+	Webhook              Webhook
+	RateLimit            RateLimit
+	// TokenType is jwt, paseto-local or paseto-public
+	TokenType string
+	// TokenPrivateKey is the hex encoded Ed25519 key of paseto-public
+	TokenPrivateKey string
+	// IpHashSecret keys the hashes client ips are stored and counted as,
+	// it is apart from AuthSecretKey so rotating tokens keeps stats intact
+	IpHashSecret string
+	// TrustedProxies are addresses or cidrs of proxies whose
+	// X-Forwarded-For is believed, none are trusted by default
+	TrustedProxies []string
 }
 
 type PostgresConfig struct {
@@ -31,6 +54,60 @@ type Smtp struct {
 	Password string
 }
 
+type Slug struct {
+	Strategy string
+	Length   int
+}
+
+type HealthCheck struct {
+	// Interval is how often every active link is checked
+	Interval    time.Duration
+	Concurrency int
+	// HostDelay is the pause between two requests to the same host
+	HostDelay time.Duration
+	Timeout   time.Duration
+	// BrokenAfter is the number of failed checks in a row after which
+	// a link is reported as broken
+	BrokenAfter int
+}
+
+type Metadata struct {
+	Concurrency int
+	Timeout     time.Duration
+	// MaxSize is how many bytes of a destination page are read at most
+	MaxSize int64
+}
+
+type Webhook struct {
+	Concurrency int
+	Timeout     time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is
+	// marked as failed
+	MaxAttempts int
+}
+
+// RateLimit is how many requests are allowed per Window. Authenticated
+// routes are counted per user, public ones per client ip.
+type RateLimit struct {
+	Window time.Duration
+	// Api applies to every authenticated route
+	Api int
+	// Create applies to creating links on top of Api
+	Create int
+	// CreateRows is how many links bulk creation and import may create,
+	// every row counts on top of the request counted by Create
+	CreateRows int
+	// Auth applies to register, verify, login and restoring accounts
+	Auth int
+	// Public applies to the other routes which need no authorization
+	Public int
+	// Redirect applies to following and unlocking short links
+	Redirect int
+	// AuthFailure is how many failed authentications a client ip may make
+	// before authenticated routes reject it
+	AuthFailure int
+}
+
 func Load(path string) Config {
 	godotenv.Load(path + "/.env") // load .env file if it exists
 
@@ -50,12 +127,174 @@ func Load(path string) Config {
 			Sender:   conf.GetString("SMTP_SENDER"),
 			Password: conf.GetString("SMTP_PASSWORD"),
 		},
-		RedisAddr:      conf.GetString("REDIS_ADDR"),
-		AuthSecretKey:  conf.GetString("AUTH_SECRET_KEY"),
-		AuthHeaderKey:  conf.GetString("AUTHORIZATION_HEADER_KEY"),
-		AuthPayloadKey: conf.GetString("AUTHORIZATION_PAYLOAD_KEY"),
-		AccessTokenDuration: conf.GetDuration("ACCESS_TOKEN_DURATION"),
+		Slug: Slug{
+			Strategy: conf.GetString("SLUG_STRATEGY"),
+			Length:   conf.GetInt("SLUG_LENGTH"),
+		},
+		HealthCheck: HealthCheck{
+			Interval:    conf.GetDuration("HEALTH_CHECK_INTERVAL"),
+			Concurrency: conf.GetInt("HEALTH_CHECK_CONCURRENCY"),
+			HostDelay:   conf.GetDuration("HEALTH_CHECK_HOST_DELAY"),
+			Timeout:     conf.GetDuration("HEALTH_CHECK_TIMEOUT"),
+			BrokenAfter: conf.GetInt("HEALTH_CHECK_BROKEN_AFTER"),
+		},
+		Metadata: Metadata{
+			Concurrency: conf.GetInt("METADATA_CONCURRENCY"),
+			Timeout:     conf.GetDuration("METADATA_TIMEOUT"),
+			MaxSize:     conf.GetInt64("METADATA_MAX_SIZE"),
+		},
+		Webhook: Webhook{
+			Concurrency: conf.GetInt("WEBHOOK_CONCURRENCY"),
+			Timeout:     conf.GetDuration("WEBHOOK_TIMEOUT"),
+			MaxAttempts: conf.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+		},
+		RateLimit: RateLimit{
+			Window:      conf.GetDuration("RATE_LIMIT_WINDOW"),
+			Api:         conf.GetInt("RATE_LIMIT_API"),
+			Create:      conf.GetInt("RATE_LIMIT_CREATE"),
+			CreateRows:  conf.GetInt("RATE_LIMIT_CREATE_ROWS"),
+			Auth:        conf.GetInt("RATE_LIMIT_AUTH"),
+			Public:      conf.GetInt("RATE_LIMIT_PUBLIC"),
+			Redirect:    conf.GetInt("RATE_LIMIT_REDIRECT"),
+			AuthFailure: conf.GetInt("RATE_LIMIT_AUTH_FAILURE"),
+		},
+		RedisAddr:            conf.GetString("REDIS_ADDR"),
+		AuthSecretKey:        conf.GetString("AUTH_SECRET_KEY"),
+		AuthHeaderKey:        conf.GetString("AUTHORIZATION_HEADER_KEY"),
+		AuthPayloadKey:       conf.GetString("AUTHORIZATION_PAYLOAD_KEY"),
+		TokenType:            conf.GetString("TOKEN_TYPE"),
+		TokenPrivateKey:      conf.GetString("TOKEN_PRIVATE_KEY"),
+		IpHashSecret:         conf.GetString("IP_HASH_SECRET"),
+		TrustedProxies:       splitList(conf.GetString("TRUSTED_PROXIES")),
+		AccessTokenDuration:  conf.GetDuration("ACCESS_TOKEN_DURATION"),
+		RefreshTokenDuration: conf.GetDuration("REFRESH_TOKEN_DURATION"),
+		PublicBaseUrl:        strings.TrimSuffix(conf.GetString("PUBLIC_BASE_URL"), "/"),
+		SweeperInterval:      conf.GetDuration("SWEEPER_INTERVAL"),
+		TrashRetention:       conf.GetDuration("TRASH_RETENTION"),
+	}
+
+	if cfg.Slug.Length == 0 {
+		cfg.Slug.Length = 7
+	}
+
+	if cfg.TokenType == "" {
+		cfg.TokenType = "jwt"
+	}
+
+	if cfg.RefreshTokenDuration <= 0 {
+		cfg.RefreshTokenDuration = 30 * 24 * time.Hour
+	}
+
+	if cfg.SweeperInterval <= 0 {
+		cfg.SweeperInterval = time.Minute
+	}
+
+	if cfg.TrashRetention <= 0 {
+		cfg.TrashRetention = 30 * 24 * time.Hour
+	}
+
+	if cfg.HealthCheck.Interval <= 0 {
+		cfg.HealthCheck.Interval = 6 * time.Hour
+	}
+
+	if cfg.HealthCheck.Concurrency <= 0 {
+		cfg.HealthCheck.Concurrency = 10
+	}
+
+	if cfg.HealthCheck.HostDelay <= 0 {
+		cfg.HealthCheck.HostDelay = time.Second
+	}
+
+	if cfg.HealthCheck.Timeout <= 0 {
+		cfg.HealthCheck.Timeout = 10 * time.Second
+	}
+
+	if cfg.HealthCheck.BrokenAfter <= 0 {
+		cfg.HealthCheck.BrokenAfter = 3
+	}
+
+	if cfg.Metadata.Concurrency <= 0 {
+		cfg.Metadata.Concurrency = 5
+	}
+
+	if cfg.Metadata.Timeout <= 0 {
+		cfg.Metadata.Timeout = 5 * time.Second
+	}
+
+	if cfg.Metadata.MaxSize <= 0 {
+		cfg.Metadata.MaxSize = 512 << 10
+	}
+
+	if cfg.Webhook.Concurrency <= 0 {
+		cfg.Webhook.Concurrency = 5
+	}
+
+	if cfg.Webhook.Timeout <= 0 {
+		cfg.Webhook.Timeout = 10 * time.Second
+	}
+
+	if cfg.Webhook.MaxAttempts <= 0 {
+		cfg.Webhook.MaxAttempts = 8
+	}
+
+	if cfg.RateLimit.Window <= 0 {
+		cfg.RateLimit.Window = time.Minute
+	}
+
+	if cfg.RateLimit.Api <= 0 {
+		cfg.RateLimit.Api = 300
+	}
+
+	if cfg.RateLimit.Create <= 0 {
+		cfg.RateLimit.Create = 30
+	}
+
+	if cfg.RateLimit.CreateRows <= 0 {
+		cfg.RateLimit.CreateRows = 10000
+	}
+
+	if cfg.RateLimit.Auth <= 0 {
+		cfg.RateLimit.Auth = 10
+	}
+
+	if cfg.RateLimit.Public <= 0 {
+		cfg.RateLimit.Public = 60
+	}
+
+	if cfg.RateLimit.Redirect <= 0 {
+		cfg.RateLimit.Redirect = 600
+	}
+
+	if cfg.RateLimit.AuthFailure <= 0 {
+		cfg.RateLimit.AuthFailure = 20
+	}
+
+	if cfg.PublicBaseUrl == "" {
+		cfg.PublicBaseUrl = "http://localhost" + cfg.HttpPort
 	}
 
 	return cfg
 }
+
+// Validate returns an error for settings the service must not start
+// without
+func (c *Config) Validate() error {
+	// without a secret anyone could hash every ipv4 address and tell the
+	// visitors of the stored clicks
+	if c.IpHashSecret == "" {
+		return errors.New("IP_HASH_SECRET is not set")
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated value, it returns nil for an empty one
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
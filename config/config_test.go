@@ -0,0 +1,17 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateIpHashSecret(t *testing.T) {
+	t.Setenv("IP_HASH_SECRET", "")
+	cfg := Load(t.TempDir())
+	require.EqualError(t, cfg.Validate(), "IP_HASH_SECRET is not set")
+
+	t.Setenv("IP_HASH_SECRET", "secret")
+	cfg = Load(t.TempDir())
+	require.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,9 @@
+-- rows in the trash would come back to life, remove them for good
+DELETE FROM "urls" WHERE "deleted_at" IS NOT NULL;
+DELETE FROM "users" WHERE "deleted_at" IS NOT NULL;
+
+DROP INDEX IF EXISTS "urls_deleted_at_idx";
+DROP INDEX IF EXISTS "users_deleted_at_idx";
+
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,6 @@
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMP WITH TIME ZONE;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMP WITH TIME ZONE;
+
+-- the purge job only looks at rows in the trash
+CREATE INDEX IF NOT EXISTS "users_deleted_at_idx" ON "users" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS "urls_deleted_at_idx" ON "urls" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
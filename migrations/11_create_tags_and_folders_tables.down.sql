@@ -0,0 +1,5 @@
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "folder_id";
+
+DROP TABLE IF EXISTS "url_tags";
+DROP TABLE IF EXISTS "tags";
+DROP TABLE IF EXISTS "folders";
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS "folders" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "name" VARCHAR NOT NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("user_id", "name")
+);
+
+CREATE TABLE IF NOT EXISTS "tags" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "name" VARCHAR NOT NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("user_id", "name")
+);
+
+CREATE TABLE IF NOT EXISTS "url_tags" (
+    "url_id" INT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    "tag_id" INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY ("url_id", "tag_id")
+);
+
+CREATE INDEX IF NOT EXISTS "url_tags_tag_id_idx" ON "url_tags" ("tag_id");
+
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "folder_id" INT REFERENCES folders(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "preview_image";
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "preview_description";
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "preview_title";
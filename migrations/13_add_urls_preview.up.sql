@@ -0,0 +1,3 @@
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "preview_title" VARCHAR;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "preview_description" VARCHAR;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "preview_image" VARCHAR;
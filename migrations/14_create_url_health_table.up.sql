@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "url_health" (
+    "url_id" INT PRIMARY KEY REFERENCES urls(id) ON DELETE CASCADE,
+    "status_code" INT NOT NULL DEFAULT 0,
+    "latency_ms" INT NOT NULL DEFAULT 0,
+    "final_url" VARCHAR,
+    "error" VARCHAR,
+    "consecutive_failures" INT NOT NULL DEFAULT 0,
+    "broken_since" TIMESTAMP WITH TIME ZONE,
+    "checked_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "url_health_checked_at_idx" ON "url_health" ("checked_at");
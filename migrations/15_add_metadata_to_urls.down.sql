@@ -0,0 +1,5 @@
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "meta_fetched_at";
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "favicon_url";
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "canonical_url";
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "meta_description";
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "meta_title";
@@ -0,0 +1,5 @@
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "meta_title" VARCHAR;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "meta_description" VARCHAR;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "canonical_url" VARCHAR;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "favicon_url" VARCHAR;
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "meta_fetched_at" TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "url" VARCHAR NOT NULL,
+    "secret" VARCHAR NOT NULL,
+    "events" VARCHAR[] NOT NULL,
+    "is_active" BOOLEAN NOT NULL DEFAULT true,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "webhooks_user_id_idx" ON "webhooks" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" SERIAL PRIMARY KEY,
+    "webhook_id" INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    "event" VARCHAR NOT NULL,
+    "event_key" VARCHAR,
+    "payload" TEXT NOT NULL,
+    "status" VARCHAR NOT NULL DEFAULT 'pending',
+    "attempts" INT NOT NULL DEFAULT 0,
+    "next_attempt_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    "status_code" INT NOT NULL DEFAULT 0,
+    "error" VARCHAR,
+    "last_attempt_at" TIMESTAMP WITH TIME ZONE,
+    "delivered_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("webhook_id", "event_key")
+);
+
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_next_attempt_at_idx" ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "api_keys" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "name" VARCHAR NOT NULL,
+    "prefix" VARCHAR NOT NULL,
+    "key_hash" VARCHAR NOT NULL UNIQUE,
+    "scopes" VARCHAR[] NOT NULL,
+    "expires_at" TIMESTAMP WITH TIME ZONE,
+    "last_used_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "api_keys_user_id_idx" ON "api_keys" ("user_id");
@@ -0,0 +1,2 @@
+ALTER TABLE "urls" DROP CONSTRAINT IF EXISTS "urls_domain_id_fkey";
+ALTER TABLE "urls" ADD CONSTRAINT "urls_domain_id_fkey" FOREIGN KEY ("domain_id") REFERENCES "domains"("id") ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- deleting a domain used to delete its links for good, past the trash.
+-- NO ACTION refuses it while any link uses the domain, unlike RESTRICT it
+-- is checked after cascades, so purging a user with domains still works
+ALTER TABLE "urls" DROP CONSTRAINT IF EXISTS "urls_domain_id_fkey";
+ALTER TABLE "urls" ADD CONSTRAINT "urls_domain_id_fkey" FOREIGN KEY ("domain_id") REFERENCES "domains"("id") ON DELETE NO ACTION;
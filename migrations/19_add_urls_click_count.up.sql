@@ -0,0 +1,4 @@
+-- click webhooks compare the counter with their thresholds instead of
+-- counting url_clicks on every redirect
+ALTER TABLE "urls" ADD COLUMN "click_count" BIGINT NOT NULL DEFAULT 0;
+UPDATE "urls" SET "click_count" = (SELECT count(1) FROM "url_clicks" WHERE "url_clicks"."url_id" = "urls"."id");
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "domains_user_id_host_key";
+DROP INDEX IF EXISTS "domains_verified_host_key";
+
+-- links are created only on verified domains, so unverified claims have none
+DELETE FROM "domains" WHERE "verified_at" IS NULL;
+ALTER TABLE "domains" ADD CONSTRAINT "domains_host_key" UNIQUE ("host");
+
+ALTER TABLE "domains" DROP COLUMN IF EXISTS "verified_at";
+ALTER TABLE "domains" DROP COLUMN IF EXISTS "verification_token";
@@ -0,0 +1,11 @@
+ALTER TABLE "domains" ADD COLUMN IF NOT EXISTS "verification_token" VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE "domains" ADD COLUMN IF NOT EXISTS "verified_at" TIMESTAMP WITH TIME ZONE;
+
+-- domains registered so far already serve links, so they are kept working
+UPDATE "domains" SET "verified_at" = "created_at";
+
+-- a host may be claimed by several users, only the one who proves it in DNS
+-- gets it, so unverified claims can not squat a host
+ALTER TABLE "domains" DROP CONSTRAINT IF EXISTS "domains_host_key";
+CREATE UNIQUE INDEX IF NOT EXISTS "domains_verified_host_key" ON "domains"("host") WHERE "verified_at" IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "domains_user_id_host_key" ON "domains"("user_id", "host");
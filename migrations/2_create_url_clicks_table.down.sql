@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "url_clicks";
\ No newline at end of file
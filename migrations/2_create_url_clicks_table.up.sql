@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "url_clicks" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "url_id" INT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    "referrer" TEXT,
+    "user_agent" TEXT,
+    "ip_hash" VARCHAR(64),
+    "accept_language" VARCHAR,
+    "clicked_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "url_clicks_url_id_clicked_at_idx" ON "url_clicks" ("url_id", "clicked_at");
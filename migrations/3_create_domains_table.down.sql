@@ -0,0 +1,6 @@
+-- the original host of the links is unknown at this point, so the slugs
+-- are restored with the default development address
+UPDATE "urls" SET "hashed_url" = 'http://localhost:8080/v1/urls/' || "hashed_url" WHERE "hashed_url" NOT LIKE '%/%';
+
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "domain_id";
+DROP TABLE IF EXISTS "domains";
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "domains" (
+    "id" SERIAL PRIMARY KEY,
+    "user_id" INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "host" VARCHAR NOT NULL UNIQUE,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "domain_id" INT REFERENCES domains(id) ON DELETE CASCADE;
+
+-- hashed_url used to keep the whole short url, e.g. http://localhost:8080/v1/urls/abcdef,
+-- from now on it keeps only the slug and the host comes from domain_id or PUBLIC_BASE_URL
+UPDATE "urls" SET "hashed_url" = regexp_replace("hashed_url", '^.*/', '') WHERE "hashed_url" LIKE '%/%';
@@ -0,0 +1,2 @@
+DROP SEQUENCE IF EXISTS "urls_slug_seq";
+DROP INDEX IF EXISTS "urls_domain_id_hashed_url_key";
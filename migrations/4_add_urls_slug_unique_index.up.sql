@@ -0,0 +1,13 @@
+-- older rows were never checked for collisions, keep the oldest link of
+-- every duplicated slug and give the others a unique suffix
+UPDATE "urls" u SET "hashed_url" = u."hashed_url" || '-' || u."id"
+WHERE EXISTS (
+    SELECT 1 FROM "urls" o
+    WHERE o."hashed_url" = u."hashed_url"
+        AND o."domain_id" IS NOT DISTINCT FROM u."domain_id"
+        AND o."id" < u."id"
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "urls_domain_id_hashed_url_key" ON "urls" (COALESCE("domain_id", 0), "hashed_url");
+
+CREATE SEQUENCE IF NOT EXISTS "urls_slug_seq";
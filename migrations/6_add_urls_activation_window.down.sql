@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "urls_active_expires_at_idx";
+
+ALTER TABLE "urls"
+    DROP COLUMN IF EXISTS "deactivated_at",
+    DROP COLUMN IF EXISTS "inactive_reason",
+    DROP COLUMN IF EXISTS "is_active",
+    DROP COLUMN IF EXISTS "starts_at";
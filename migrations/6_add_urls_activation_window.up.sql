@@ -0,0 +1,8 @@
+ALTER TABLE "urls"
+    ADD COLUMN IF NOT EXISTS "starts_at" TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS "is_active" BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS "inactive_reason" VARCHAR,
+    ADD COLUMN IF NOT EXISTS "deactivated_at" TIMESTAMP WITH TIME ZONE;
+
+-- the sweeper only looks at active links
+CREATE INDEX IF NOT EXISTS "urls_active_expires_at_idx" ON "urls" ("expires_at") WHERE "is_active";
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "url_rules" (
+    "id" SERIAL PRIMARY KEY,
+    "url_id" INT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    "position" INT NOT NULL,
+    "os" VARCHAR,
+    "device" VARCHAR,
+    "language" VARCHAR,
+    -- minutes since midnight in "timezone", the window may wrap midnight
+    "time_from" SMALLINT,
+    "time_to" SMALLINT,
+    "timezone" VARCHAR,
+    "target_url" TEXT NOT NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "url_rules_url_id_position_idx" ON "url_rules" ("url_id", "position");
@@ -0,0 +1,5 @@
+ALTER TABLE "url_clicks" DROP COLUMN IF EXISTS "destination_id";
+
+ALTER TABLE "urls" DROP COLUMN IF EXISTS "sticky_destinations";
+
+DROP TABLE IF EXISTS "url_destinations";
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "url_destinations" (
+    "id" SERIAL PRIMARY KEY,
+    "url_id" INT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    "target_url" TEXT NOT NULL,
+    "weight" INT NOT NULL CHECK ("weight" > 0),
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "url_destinations_url_id_idx" ON "url_destinations" ("url_id");
+
+ALTER TABLE "urls" ADD COLUMN IF NOT EXISTS "sticky_destinations" BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE "url_clicks" ADD COLUMN IF NOT EXISTS "destination_id" INT REFERENCES url_destinations(id) ON DELETE SET NULL;
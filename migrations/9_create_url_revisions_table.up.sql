@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "url_revisions" (
+    "id" SERIAL PRIMARY KEY,
+    "url_id" INT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    "revision" INT NOT NULL,
+    -- the user who made the change
+    "user_id" INT REFERENCES users(id) ON DELETE SET NULL,
+    "original_url" TEXT NOT NULL,
+    "hashed_url" VARCHAR NOT NULL,
+    "max_clicks" INT,
+    "starts_at" TIMESTAMP WITH TIME ZONE,
+    "expires_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("url_id", "revision")
+);
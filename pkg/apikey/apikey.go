@@ -0,0 +1,73 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeStatsRead  = "stats:read"
+
+	// Header is where clients send their key
+	Header = "X-API-Key"
+
+	// prefix makes keys recognizable, e.g. by secret scanners
+	prefix = "lk_"
+	// PrefixLength is how much of a key is kept in plain text, so users
+	// can tell their keys apart
+	PrefixLength = len(prefix) + 8
+)
+
+var Scopes = []string{
+	ScopeLinksRead,
+	ScopeLinksWrite,
+	ScopeStatsRead,
+}
+
+func IsScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate returns a new random key, only its Hash should be stored
+func Generate() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// Hash returns the hex sha256 of the key. Keys are random enough that a
+// slow password hash would add nothing, and a plain digest can be looked
+// up directly.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Prefix returns the visible start of the key
+func Prefix(key string) string {
+	if len(key) < PrefixLength {
+		return key
+	}
+	return key[:PrefixLength]
+}
+
+// Valid reports whether the key looks like one made by Generate, so
+// garbage is rejected without a database lookup
+func Valid(key string) bool {
+	if !strings.HasPrefix(key, prefix) || len(key) != len(prefix)+48 {
+		return false
+	}
+	_, err := hex.DecodeString(key[len(prefix):])
+	return err == nil
+}
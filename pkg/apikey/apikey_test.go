@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	k1, err := Generate()
+	require.NoError(t, err)
+	k2, err := Generate()
+	require.NoError(t, err)
+
+	require.NotEqual(t, k1, k2)
+	require.True(t, Valid(k1))
+	require.Len(t, Prefix(k1), PrefixLength)
+	require.Equal(t, k1[:PrefixLength], Prefix(k1))
+
+	require.Equal(t, Hash(k1), Hash(k1))
+	require.NotEqual(t, Hash(k1), Hash(k2))
+	require.Len(t, Hash(k1), 64)
+}
+
+func TestValid(t *testing.T) {
+	require.False(t, Valid(""))
+	require.False(t, Valid("lk_"))
+	require.False(t, Valid("xx_0123456789abcdef0123456789abcdef0123456789abcdef"))
+	require.False(t, Valid("lk_0123456789abcdef0123456789abcdef0123456789abcdeg"))
+	require.True(t, Valid("lk_0123456789abcdef0123456789abcdef0123456789abcdef"))
+}
+
+func TestIsScope(t *testing.T) {
+	require.True(t, IsScope(ScopeLinksRead))
+	require.True(t, IsScope(ScopeStatsRead))
+	require.False(t, IsScope("links:*"))
+	require.False(t, IsScope(""))
+}
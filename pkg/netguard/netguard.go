@@ -0,0 +1,131 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for connections to addresses which are
+// not reachable from the internet, like loopback or private ones. Outgoing
+// requests to user given urls must not reach the internal network.
+var ErrForbiddenAddress = errors.New("address is not public")
+
+// blocked are ranges which IsPrivate and friends of net.IP do not cover
+var blocked = mustParseCIDRs(
+	"0.0.0.0/8",      // this network
+	"100.64.0.0/10",  // carrier-grade nat
+	"192.0.0.0/24",   // ietf protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved and broadcast
+	"64:ff9b::/96",   // nat64, embeds ipv4 addresses
+	"64:ff9b:1::/48", // local-use nat64
+	"2002::/16",      // 6to4, embeds ipv4 addresses
+	"2001::/32",      // teredo
+	"fec0::/10",      // deprecated site-local
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Public reports whether ip is a public unicast address, loopback,
+// private, link-local, unspecified and multicast addresses are not
+func Public(ip net.IP) bool {
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+
+	for _, n := range blocked {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Control refuses connections to addresses which are not Public. As
+// net.Dialer.Control it is called with the resolved address of every
+// connection, so redirects and dns rebinding can not get around it.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !Public(net.ParseIP(host)) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+// NewDialer returns a dialer which connects only to public addresses
+func NewDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}
+}
+
+// NewClient returns a client which connects through the dialer. It never
+// uses a proxy, the dialer would check the address of the proxy instead of
+// the one of the destination.
+func NewClient(timeout time.Duration, dialer *net.Dialer) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// CheckHost resolves the host and returns ErrForbiddenAddress when any of
+// its addresses is not Public. It is meant for rejecting user input early,
+// connections still have to be made with Control since dns answers change.
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !Public(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range addrs {
+		if !Public(a.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+
+	return nil
+}
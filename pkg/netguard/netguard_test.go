@@ -0,0 +1,69 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.public, Public(net.ParseIP(tt.ip)), tt.ip)
+	}
+	require.False(t, Public(nil))
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, CheckHost(ctx, "93.184.216.34"))
+	require.ErrorIs(t, CheckHost(ctx, "169.254.169.254"), ErrForbiddenAddress)
+	require.ErrorIs(t, CheckHost(ctx, "::1"), ErrForbiddenAddress)
+	require.ErrorIs(t, CheckHost(ctx, "localhost"), ErrForbiddenAddress)
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(time.Second, &net.Dialer{}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// the test server listens on 127.0.0.1
+	_, err = NewClient(time.Second, NewDialer()).Get(server.URL)
+	require.True(t, errors.Is(err, ErrForbiddenAddress))
+}
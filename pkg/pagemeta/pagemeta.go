@@ -0,0 +1,217 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// MaxTitleLength and MaxDescriptionLength are in characters, longer
+	// values are cut
+	MaxTitleLength       = 300
+	MaxDescriptionLength = 1000
+	// MaxUrlLength is the longest canonical or favicon url that is kept
+	MaxUrlLength = 2048
+
+	userAgent = "Mozilla/5.0 (compatible; LinkMetadataFetcher/1.0)"
+)
+
+var (
+	ErrNotHTML   = errors.New("destination is not an html page")
+	ErrBadStatus = errors.New("destination answered with an error")
+)
+
+type Metadata struct {
+	Title        string
+	Description  string
+	CanonicalUrl string
+	FaviconUrl   string
+}
+
+// Fetcher downloads pages and extracts their metadata, at most maxSize
+// bytes of a page are read
+type Fetcher struct {
+	client  *http.Client
+	maxSize int64
+}
+
+// NewFetcher connects through the dialer, pages are fetched for any url a
+// user gives, so it should be netguard.NewDialer outside of tests
+func NewFetcher(timeout time.Duration, maxSize int64, dialer *net.Dialer) *Fetcher {
+	return &Fetcher{
+		client:  netguard.NewClient(timeout, dialer),
+		maxSize: maxSize,
+	}
+}
+
+// Fetch requests the page and parses its head. Redirects are followed,
+// relative urls are resolved against the address the page was served from.
+func (f *Fetcher) Fetch(ctx context.Context, rawUrl string) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %s", ErrBadStatus, resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("%w: %q", ErrNotHTML, mediaType)
+	}
+
+	return Parse(io.LimitReader(resp.Body, f.maxSize), resp.Request.URL), nil
+}
+
+// Parse reads the head of an html document, the rest of the document is
+// not read. When the page has no icon link, /favicon.ico of the host is
+// assumed.
+func Parse(r io.Reader, base *url.URL) *Metadata {
+	var (
+		m         Metadata
+		ogTitle   string
+		ogDesc    string
+		icon      string
+		touchIcon string
+		inTitle   bool
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break loop
+		case html.TextToken:
+			if inTitle {
+				m.Title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Body {
+				break loop
+			}
+			if tag == atom.Title && tt == html.StartTagToken {
+				inTitle = m.Title == ""
+				continue
+			}
+
+			attrs := make(map[string]string)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+
+			switch tag {
+			case atom.Base:
+				if href := resolve(base, attrs["href"]); href != "" {
+					base, _ = url.Parse(href)
+				}
+			case atom.Meta:
+				switch strings.ToLower(attrs["name"] + attrs["property"]) {
+				case "description":
+					m.Description = attrs["content"]
+				case "og:title":
+					ogTitle = attrs["content"]
+				case "og:description":
+					ogDesc = attrs["content"]
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch rel {
+					case "canonical":
+						m.CanonicalUrl = resolve(base, attrs["href"])
+					case "icon":
+						if icon == "" {
+							icon = resolve(base, attrs["href"])
+						}
+					case "apple-touch-icon":
+						if touchIcon == "" {
+							touchIcon = resolve(base, attrs["href"])
+						}
+					}
+				}
+			}
+		}
+	}
+
+	m.Title = clean(m.Title, MaxTitleLength)
+	if m.Title == "" {
+		m.Title = clean(ogTitle, MaxTitleLength)
+	}
+	m.Description = clean(m.Description, MaxDescriptionLength)
+	if m.Description == "" {
+		m.Description = clean(ogDesc, MaxDescriptionLength)
+	}
+
+	m.FaviconUrl = icon
+	if m.FaviconUrl == "" {
+		m.FaviconUrl = touchIcon
+	}
+	if m.FaviconUrl == "" {
+		m.FaviconUrl = resolve(base, "/favicon.ico")
+	}
+
+	return &m
+}
+
+// resolve returns the absolute http(s) form of href, or an empty string
+// for other schemes and too long urls
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	u, err := base.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	s := u.String()
+	if len(s) > MaxUrlLength {
+		return ""
+	}
+	return s
+}
+
+// clean collapses whitespace and cuts s to max characters
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+	if utf8.RuneCountInString(s) > max {
+		s = strings.TrimSpace(string([]rune(s)[:max]))
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/post")
+	require.NoError(t, err)
+
+	page := `<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="utf-8">
+			<title>
+				Hello &amp; welcome
+			</title>
+			<meta name="Description" content="  A post   about things ">
+			<meta property="og:title" content="OG title">
+			<link rel="canonical" href="/blog/post?ref=canonical">
+			<link rel="shortcut icon" href="static/icon.png">
+			<link rel="icon" href="javascript:alert(1)">
+		</head>
+		<body>
+			<title>Not this one</title>
+			<meta name="description" content="nor this">
+		</body>
+		</html>`
+
+	m := Parse(strings.NewReader(page), base)
+	require.Equal(t, "Hello & welcome", m.Title)
+	require.Equal(t, "A post about things", m.Description)
+	require.Equal(t, "https://example.com/blog/post?ref=canonical", m.CanonicalUrl)
+	require.Equal(t, "https://example.com/blog/static/icon.png", m.FaviconUrl)
+}
+
+func TestParseFallbacks(t *testing.T) {
+	base, err := url.Parse("http://example.com/a/b")
+	require.NoError(t, err)
+
+	page := `<head>
+		<base href="https://cdn.example.com/assets/">
+		<meta property="og:title" content="OG title">
+		<meta property="og:description" content="OG description">
+		<link rel="apple-touch-icon" href="touch.png">
+		</head>`
+
+	m := Parse(strings.NewReader(page), base)
+	require.Equal(t, "OG title", m.Title)
+	require.Equal(t, "OG description", m.Description)
+	require.Empty(t, m.CanonicalUrl)
+	require.Equal(t, "https://cdn.example.com/assets/touch.png", m.FaviconUrl)
+
+	m = Parse(strings.NewReader(`<title>`+strings.Repeat("a", MaxTitleLength+10)+`</title>`), base)
+	require.Len(t, m.Title, MaxTitleLength)
+	require.Equal(t, "http://example.com/favicon.ico", m.FaviconUrl)
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Page</title></head><body></body></html>`))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>` + strings.Repeat("x", 1024) + `Hidden</title></head></html>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := NewFetcher(100*time.Millisecond, 512, &net.Dialer{})
+
+	m, err := f.Fetch(context.Background(), server.URL+"/moved")
+	require.NoError(t, err)
+	require.Equal(t, "Page", m.Title)
+	require.Equal(t, server.URL+"/favicon.ico", m.FaviconUrl)
+
+	m, err = f.Fetch(context.Background(), server.URL+"/big")
+	require.NoError(t, err)
+	require.NotContains(t, m.Title, "Hidden")
+
+	_, err = f.Fetch(context.Background(), server.URL+"/image")
+	require.True(t, errors.Is(err, ErrNotHTML))
+
+	_, err = f.Fetch(context.Background(), server.URL+"/missing")
+	require.True(t, errors.Is(err, ErrBadStatus))
+
+	_, err = f.Fetch(context.Background(), server.URL+"/slow")
+	require.Error(t, err)
+}
+
+func TestFetchPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<head><title>Internal</title></head>`))
+	}))
+	defer server.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	// the test servers listen on 127.0.0.1
+	f := NewFetcher(time.Second, 512, netguard.NewDialer())
+
+	m, err := f.Fetch(context.Background(), server.URL)
+	require.True(t, errors.Is(err, netguard.ErrForbiddenAddress))
+	require.Nil(t, m)
+
+	// redirects are checked as well
+	f = NewFetcher(time.Second, 512, &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			if address == public.Listener.Addr().String() {
+				return nil
+			}
+			return netguard.Control(network, address, c)
+		},
+	})
+	_, err = f.Fetch(context.Background(), public.URL)
+	require.True(t, errors.Is(err, netguard.ErrForbiddenAddress))
+}
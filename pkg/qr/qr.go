@@ -0,0 +1,164 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	MinSize = 64
+	MaxSize = 2048
+)
+
+var (
+	ErrInvalidFormat = errors.New("qr code format must be png or svg")
+	ErrInvalidSize   = fmt.Errorf("qr code size must be between %d and %d", MinSize, MaxSize)
+	ErrInvalidLevel  = errors.New("qr code level must be one of low, medium, high, highest")
+	ErrInvalidColor  = errors.New("qr code colour must be a hex value like #000 or #000000")
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"low":     qrcode.Low,
+	"medium":  qrcode.Medium,
+	"high":    qrcode.High,
+	"highest": qrcode.Highest,
+}
+
+type Options struct {
+	Content    string
+	Format     string
+	Size       int
+	Level      string
+	Foreground string
+	Background string
+	QuietZone  bool
+}
+
+// Validate checks the options and fills in defaults for the empty ones
+func (o *Options) Validate() error {
+	if o.Format == "" {
+		o.Format = FormatPNG
+	}
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return ErrInvalidFormat
+	}
+
+	if o.Size == 0 {
+		o.Size = 256
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return ErrInvalidSize
+	}
+
+	if o.Level == "" {
+		o.Level = "medium"
+	}
+	if _, ok := levels[o.Level]; !ok {
+		return ErrInvalidLevel
+	}
+
+	if o.Foreground == "" {
+		o.Foreground = "#000000"
+	}
+	if o.Background == "" {
+		o.Background = "#ffffff"
+	}
+	// colours are normalized to #rrggbb, so they can be put into svg as is
+	for _, c := range []*string{&o.Foreground, &o.Background} {
+		rgba, err := ParseHexColor(*c)
+		if err != nil {
+			return err
+		}
+		*c = fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+	}
+
+	return nil
+}
+
+// ContentType returns the mime type of the generated image
+func (o *Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Generate renders the QR code described by the options. Options must be
+// validated first.
+func Generate(o *Options) ([]byte, error) {
+	q, err := qrcode.New(o.Content, levels[o.Level])
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = !o.QuietZone
+
+	fg, err := ParseHexColor(o.Foreground)
+	if err != nil {
+		return nil, err
+	}
+	bg, err := ParseHexColor(o.Background)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Format == FormatSVG {
+		return svg(q.Bitmap(), o.Size, o.Foreground, o.Background), nil
+	}
+
+	q.ForegroundColor = fg
+	q.BackgroundColor = bg
+	return q.PNG(o.Size)
+}
+
+// svg draws every set module of the bitmap as a single path, which keeps
+// the output small compared to one rect per module
+func svg(bitmap [][]bool, size int, fg, bg string) []byte {
+	var b bytes.Buffer
+	modules := len(bitmap)
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, bg)
+	b.WriteString(`<path fill="` + fg + `" d="`)
+	for y, row := range bitmap {
+		for x, set := range row {
+			if set {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+
+	return b.Bytes()
+}
+
+// ParseHexColor parses colours in #rgb or #rrggbb form, the leading # is optional
+func ParseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBA{
+		R: uint8(v >> 16),
+		G: uint8(v >> 8),
+		B: uint8(v),
+		A: 0xff,
+	}, nil
+}
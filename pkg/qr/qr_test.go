@@ -0,0 +1,57 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePNG(t *testing.T) {
+	o := Options{
+		Content:   "https://example.com/abc123",
+		QuietZone: true,
+	}
+	require.NoError(t, o.Validate())
+	require.Equal(t, "image/png", o.ContentType())
+
+	img, err := Generate(&o)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(img, []byte("\x89PNG")))
+}
+
+func TestGenerateSVG(t *testing.T) {
+	o := Options{
+		Content:    "https://example.com/abc123",
+		Format:     FormatSVG,
+		Size:       512,
+		Level:      "high",
+		Foreground: "f00",
+		Background: "#00FF00",
+	}
+	require.NoError(t, o.Validate())
+	require.Equal(t, "#ff0000", o.Foreground)
+	require.Equal(t, "#00ff00", o.Background)
+
+	img, err := Generate(&o)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(img, []byte("<svg")))
+	require.Contains(t, string(img), `fill="#ff0000"`)
+}
+
+func TestValidateOptions(t *testing.T) {
+	require.ErrorIs(t, (&Options{Format: "gif"}).Validate(), ErrInvalidFormat)
+	require.ErrorIs(t, (&Options{Size: 10}).Validate(), ErrInvalidSize)
+	require.ErrorIs(t, (&Options{Level: "max"}).Validate(), ErrInvalidLevel)
+	require.ErrorIs(t, (&Options{Foreground: "#zzzzzz"}).Validate(), ErrInvalidColor)
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, err := ParseHexColor("#1166f0")
+	require.NoError(t, err)
+	require.Equal(t, color.RGBA{R: 0x11, G: 0x66, B: 0xf0, A: 0xff}, c)
+
+	_, err = ParseHexColor("12345")
+	require.Error(t, err)
+}
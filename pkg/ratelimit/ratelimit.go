@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const KeyPrefix = "rate_limit_"
+
+// Store keeps the counters, storage.InMemoryStorageI satisfies it
+type Store interface {
+	Get(key string) (string, error)
+	// IncrBy increments the counter by n and returns its new value, the
+	// expiration is set when the counter is created
+	IncrBy(key string, n int64, exp time.Duration) (int64, error)
+}
+
+// Limiter is a sliding window limiter. Requests are counted in fixed
+// windows and the count of the previous window is weighted by how much of
+// it still overlaps the sliding one, so bursts at the edge of two windows
+// do not get twice the limit through.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the current window ends
+	Reset time.Duration
+}
+
+func New(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow counts a request of the key and reports whether it is within
+// limit requests per window. Rejected requests are counted as well, so
+// clients which keep retrying stay limited.
+func (l *Limiter) Allow(key string, limit int, window time.Duration) (*Result, error) {
+	return l.AllowN(key, 1, limit, window)
+}
+
+// AllowN is Allow for n requests at once, e.g. the rows of a bulk request.
+func (l *Limiter) AllowN(key string, n, limit int, window time.Duration) (*Result, error) {
+	now := l.now()
+	start := now.Truncate(window)
+
+	count, err := l.store.IncrBy(windowKey(key, start), int64(n), 2*window)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.result(key, count, limit, window, now), nil
+}
+
+// Check reports whether the key is still within limit without counting a
+// request, for limits which count only some of the requests with Allow.
+// Counters which can not be read are taken as unused.
+func (l *Limiter) Check(key string, limit int, window time.Duration) *Result {
+	now := l.now()
+	start := now.Truncate(window)
+
+	// one more request has to fit in, as it would for Allow
+	count := int64(1)
+	value, err := l.store.Get(windowKey(key, start))
+	if err == nil {
+		current, _ := strconv.ParseInt(value, 10, 64)
+		count += current
+	}
+
+	return l.result(key, count, limit, window, now)
+}
+
+func (l *Limiter) result(key string, count int64, limit int, window time.Duration, now time.Time) *Result {
+	start := now.Truncate(window)
+
+	// a missing previous window just was not used
+	var previous int64
+	if value, err := l.store.Get(windowKey(key, start.Add(-window))); err == nil {
+		previous, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	elapsed := now.Sub(start)
+	weight := float64(window-elapsed) / float64(window)
+	estimated := int(math.Ceil(float64(previous)*weight)) + int(count)
+
+	remaining := limit - estimated
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &Result{
+		Allowed:   estimated <= limit,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     window - elapsed,
+	}
+}
+
+func windowKey(key string, start time.Time) string {
+	return fmt.Sprintf("%s%s_%d", KeyPrefix, key, start.Unix())
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore map[string]int64
+
+func (m memoryStore) Get(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+func (m memoryStore) IncrBy(key string, n int64, exp time.Duration) (int64, error) {
+	m[key] += n
+	return m[key], nil
+}
+
+func TestAllow(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	limiter := New(memoryStore{})
+	limiter.now = func() time.Time { return now }
+
+	for i := 1; i <= 3; i++ {
+		result, err := limiter.Allow("user_1", 3, time.Minute)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3-i, result.Remaining)
+		require.Equal(t, time.Minute, result.Reset)
+	}
+
+	result, err := limiter.Allow("user_1", 3, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	// other keys have their own counters
+	result, err = limiter.Allow("user_2", 3, time.Minute)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// right after the window ends the previous one still counts in full
+	now = start.Add(time.Minute)
+	result, err = limiter.Allow("user_1", 3, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	// 4 requests weighted by a quarter leave room for one more
+	now = start.Add(time.Minute + 45*time.Second)
+	result, err = limiter.Allow("user_1", 3, time.Minute)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, 15*time.Second, result.Reset)
+
+	now = start.Add(3 * time.Minute)
+	result, err = limiter.Allow("user_1", 3, time.Minute)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 2, result.Remaining)
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := New(memoryStore{})
+	limiter.now = func() time.Time { return now }
+
+	// checking does not count
+	for i := 0; i < 5; i++ {
+		result := limiter.Check("ip_1", 2, time.Minute)
+		require.True(t, result.Allowed)
+		require.Equal(t, 1, result.Remaining)
+	}
+
+	_, err := limiter.Allow("ip_1", 2, time.Minute)
+	require.NoError(t, err)
+	require.True(t, limiter.Check("ip_1", 2, time.Minute).Allowed)
+
+	_, err = limiter.Allow("ip_1", 2, time.Minute)
+	require.NoError(t, err)
+	result := limiter.Check("ip_1", 2, time.Minute)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+}
+
+func TestAllowN(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := New(memoryStore{})
+	limiter.now = func() time.Time { return now }
+
+	result, err := limiter.AllowN("user_1", 800, 1000, time.Minute)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 200, result.Remaining)
+
+	// every one of the n requests counts, not just the call
+	result, err = limiter.AllowN("user_1", 201, 1000, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	result, err = limiter.Allow("user_1", 1000, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
@@ -0,0 +1,158 @@
+package slug
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/base58-go"
+)
+
+const (
+	StrategyRandom   = "random"
+	StrategySequence = "sequence"
+	StrategyHash     = "hash"
+
+	MinLength = 4
+	MaxLength = 32
+
+	// MaxAttempts is how many times a caller should ask for a new slug
+	// after a unique violation before giving up
+	MaxAttempts = 5
+
+	// 58^11 does not fit into uint64, so longer sequence slugs are impossible
+	maxSequenceLength = 11
+)
+
+// alphabet is the bitcoin base58 alphabet, it has no 0, O, I and l,
+// so slugs can be read out loud and typed without mistakes
+const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var encoding = base58.BitcoinEncoding
+
+// Generator generates slugs for short urls
+type Generator interface {
+	// Generate returns a slug for the url, attempt is 0 on the first call
+	// and is increased by the caller on every collision
+	Generate(originalUrl string, attempt int) (string, error)
+}
+
+// SequenceFunc returns the next value of a database sequence
+type SequenceFunc func() (int64, error)
+
+// NewGenerator creates the generator of the given strategy. next is only
+// used by the sequence strategy.
+func NewGenerator(strategy string, length int, next SequenceFunc) (Generator, error) {
+	if length < MinLength || length > MaxLength {
+		return nil, fmt.Errorf("invalid slug length: must be between %d and %d", MinLength, MaxLength)
+	}
+
+	switch strategy {
+	case StrategyRandom, "":
+		return &randomGenerator{length: length}, nil
+	case StrategySequence:
+		if next == nil {
+			return nil, fmt.Errorf("sequence slug generator needs a sequence")
+		}
+		if length > maxSequenceLength {
+			return nil, fmt.Errorf("invalid slug length: sequence slugs can be at most %d characters", maxSequenceLength)
+		}
+		offset := uint64(1)
+		for i := 1; i < length; i++ {
+			offset *= uint64(len(alphabet))
+		}
+		return &sequenceGenerator{offset: offset, next: next}, nil
+	case StrategyHash:
+		return &hashGenerator{length: length}, nil
+	}
+
+	return nil, fmt.Errorf("unknown slug strategy: %s", strategy)
+}
+
+// randomGenerator picks every character with crypto/rand
+type randomGenerator struct {
+	length int
+}
+
+func (g *randomGenerator) Generate(originalUrl string, attempt int) (string, error) {
+	const maxByte = 256 - 256%len(alphabet) // avoid modulo bias
+
+	slug := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+	for len(slug) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= maxByte {
+				continue
+			}
+			slug = append(slug, alphabet[int(b)%len(alphabet)])
+			if len(slug) == g.length {
+				break
+			}
+		}
+	}
+
+	return string(slug), nil
+}
+
+// sequenceGenerator encodes the next value of a database sequence, so it
+// never collides with its own slugs, only with custom ones
+type sequenceGenerator struct {
+	// offset is 58^(length-1), the smallest number with length digits,
+	// so slugs are never shorter than the configured length
+	offset uint64
+	next   SequenceFunc
+}
+
+func (g *sequenceGenerator) Generate(originalUrl string, attempt int) (string, error) {
+	n, err := g.next()
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoding.EncodeUint64(g.offset + uint64(n))), nil
+}
+
+// hashGenerator derives the slug from the url itself, so the same url
+// gets the same slug until it collides
+type hashGenerator struct {
+	length int
+}
+
+func (g *hashGenerator) Generate(originalUrl string, attempt int) (string, error) {
+	input := originalUrl
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+
+	var slug []byte
+	for i := 0; i+8 <= len(sum) && len(slug) < g.length; i += 8 {
+		slug = append(slug, encoding.EncodeUint64(binary.BigEndian.Uint64(sum[i:i+8]))...)
+	}
+	if len(slug) > g.length {
+		slug = slug[:g.length]
+	}
+
+	return string(slug), nil
+}
+
+// Url returns the public address of slug s. Links of branded domains are
+// served from host with the scheme of baseUrl, others from baseUrl itself.
+func Url(baseUrl, host, s string) string {
+	if host == "" {
+		return baseUrl + "/" + s
+	}
+
+	scheme := "https"
+	if i := strings.Index(baseUrl, "://"); i > 0 {
+		scheme = baseUrl[:i]
+	}
+
+	return scheme + "://" + host + "/" + s
+}
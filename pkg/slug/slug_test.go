@@ -0,0 +1,85 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomGenerator(t *testing.T) {
+	g, err := NewGenerator(StrategyRandom, 8, nil)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		s, err := g.Generate("https://example.com", 0)
+		require.NoError(t, err)
+		require.Len(t, s, 8)
+		for _, c := range s {
+			require.True(t, strings.ContainsRune(alphabet, c))
+		}
+		require.False(t, seen[s])
+		seen[s] = true
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	var n int64
+	next := func() (int64, error) {
+		n++
+		return n, nil
+	}
+
+	g, err := NewGenerator(StrategySequence, 6, next)
+	require.NoError(t, err)
+
+	s1, err := g.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	s2, err := g.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	require.Len(t, s1, 6)
+	require.Len(t, s2, 6)
+	require.NotEqual(t, s1, s2)
+
+	_, err = NewGenerator(StrategySequence, 12, next)
+	require.Error(t, err)
+	_, err = NewGenerator(StrategySequence, 6, nil)
+	require.Error(t, err)
+}
+
+func TestHashGenerator(t *testing.T) {
+	g, err := NewGenerator(StrategyHash, 10, nil)
+	require.NoError(t, err)
+
+	s1, err := g.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	s2, err := g.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	s3, err := g.Generate("https://example.com", 1)
+	require.NoError(t, err)
+	require.Len(t, s1, 10)
+	require.Equal(t, s1, s2)
+	require.NotEqual(t, s1, s3)
+
+	long, err := NewGenerator(StrategyHash, MaxLength, nil)
+	require.NoError(t, err)
+	s4, err := long.Generate("https://example.com", 0)
+	require.NoError(t, err)
+	require.Len(t, s4, MaxLength)
+}
+
+func TestNewGeneratorValidation(t *testing.T) {
+	_, err := NewGenerator("uuid", 8, nil)
+	require.Error(t, err)
+	_, err = NewGenerator(StrategyRandom, MinLength-1, nil)
+	require.Error(t, err)
+	_, err = NewGenerator(StrategyRandom, MaxLength+1, nil)
+	require.Error(t, err)
+}
+
+func TestUrl(t *testing.T) {
+	require.Equal(t, "http://localhost:8000/abc", Url("http://localhost:8000", "", "abc"))
+	require.Equal(t, "http://go.example.com/abc", Url("http://localhost:8000", "go.example.com", "abc"))
+	require.Equal(t, "https://go.example.com/abc", Url("https://short.example.com", "go.example.com", "abc"))
+}
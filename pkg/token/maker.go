@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type Maker interface {
 	// CreateToken creates a new token for a specific username and duration
 	CreateToken(tokenParams *TokenParams) (string, *Payload, error)
@@ -7,3 +9,25 @@ type Maker interface {
 	//  VerifyToken checks if the input token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
+
+const (
+	TypeJWT          = "jwt"
+	TypePasetoLocal  = "paseto-local"
+	TypePasetoPublic = "paseto-public"
+)
+
+// NewMaker creates the maker of the token type, an empty type means JWT.
+// JWT and PASETO local tokens are made with the secret key, PASETO public
+// tokens with the hex encoded Ed25519 private key.
+func NewMaker(tokenType, secretKey, privateKey string) (Maker, error) {
+	switch tokenType {
+	case TypeJWT, "":
+		return NewJWTMaker(secretKey)
+	case TypePasetoLocal:
+		return NewPasetoLocalMaker(secretKey)
+	case TypePasetoPublic:
+		return NewPasetoPublicMaker(privateKey)
+	default:
+		return nil, fmt.Errorf("unknown token type %q", tokenType)
+	}
+}
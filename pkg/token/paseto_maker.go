@@ -0,0 +1,287 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	pasetoLocalHeader  = "v4.local."
+	pasetoPublicHeader = "v4.public."
+
+	pasetoNonceSize = 32
+	pasetoMacSize   = 32
+)
+
+var b64 = base64.RawURLEncoding
+
+// PasetoLocalMaker is a PASETO v4.local maker, tokens are encrypted with
+// XChaCha20 and authenticated with keyed BLAKE2b, so their payload can be
+// read only with the key
+type PasetoLocalMaker struct {
+	key []byte
+}
+
+// NewPasetoLocalMaker creates a new PasetoLocalMaker, the 32 byte key is
+// derived from the secret with BLAKE2b
+func NewPasetoLocalMaker(secretKey string) (Maker, error) {
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	}
+
+	key := blake2b.Sum256([]byte(secretKey))
+	return &PasetoLocalMaker{key[:]}, nil
+}
+
+// CreateToken creates a new token for a specific username and duration
+func (maker *PasetoLocalMaker) CreateToken(tokenParams *TokenParams) (string, *Payload, error) {
+	payload, err := NewPayload(tokenParams)
+	if err != nil {
+		return "", payload, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", payload, err
+	}
+
+	nonce := make([]byte, pasetoNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.encrypt(message, nonce)
+	return token, payload, err
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *PasetoLocalMaker) VerifyToken(token string) (*Payload, error) {
+	message, err := maker.decrypt(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return parsePasetoPayload(message)
+}
+
+func (maker *PasetoLocalMaker) encrypt(message, nonce []byte) (string, error) {
+	encryptionKey, counterNonce, authKey, err := maker.splitKey(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	mac, err := blake2b.New256(authKey)
+	if err != nil {
+		return "", err
+	}
+	mac.Write(pae([]byte(pasetoLocalHeader), nonce, ciphertext, nil, nil))
+
+	body := append(append(append([]byte{}, nonce...), ciphertext...), mac.Sum(nil)...)
+	return pasetoLocalHeader + b64.EncodeToString(body), nil
+}
+
+func (maker *PasetoLocalMaker) decrypt(token string) ([]byte, error) {
+	body, err := pasetoBody(token, pasetoLocalHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < pasetoNonceSize+pasetoMacSize {
+		return nil, ErrInvalidToken
+	}
+
+	nonce := body[:pasetoNonceSize]
+	ciphertext := body[pasetoNonceSize : len(body)-pasetoMacSize]
+	tag := body[len(body)-pasetoMacSize:]
+
+	encryptionKey, counterNonce, authKey, err := maker.splitKey(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := blake2b.New256(authKey)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(pae([]byte(pasetoLocalHeader), nonce, ciphertext, nil, nil))
+	if subtle.ConstantTimeCompare(mac.Sum(nil), tag) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+
+	return message, nil
+}
+
+// splitKey derives the encryption key with its nonce and the
+// authentication key of a token from the nonce of the token
+func (maker *PasetoLocalMaker) splitKey(nonce []byte) ([]byte, []byte, []byte, error) {
+	encryption, err := blake2b.New(56, maker.key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	encryption.Write([]byte("paseto-encryption-key"))
+	encryption.Write(nonce)
+	tmp := encryption.Sum(nil)
+
+	auth, err := blake2b.New256(maker.key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	auth.Write([]byte("paseto-auth-key-for-aead"))
+	auth.Write(nonce)
+
+	return tmp[:chacha20.KeySize], tmp[chacha20.KeySize:], auth.Sum(nil), nil
+}
+
+// PasetoPublicMaker is a PASETO v4.public maker, tokens are signed with
+// Ed25519 and can be verified by anyone with the public key
+type PasetoPublicMaker struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker from a hex encoded
+// Ed25519 private key, either the 32 byte seed or the 64 byte key
+func NewPasetoPublicMaker(privateKey string) (Maker, error) {
+	key, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	switch len(key) {
+	case ed25519.SeedSize:
+		return newPasetoPublicMaker(ed25519.NewKeyFromSeed(key)), nil
+	case ed25519.PrivateKeySize:
+		return newPasetoPublicMaker(ed25519.PrivateKey(key)), nil
+	default:
+		return nil, fmt.Errorf("invalid key size: must be %d or %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+func newPasetoPublicMaker(privateKey ed25519.PrivateKey) *PasetoPublicMaker {
+	return &PasetoPublicMaker{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+}
+
+// CreateToken creates a new token for a specific username and duration
+func (maker *PasetoPublicMaker) CreateToken(tokenParams *TokenParams) (string, *Payload, error) {
+	payload, err := NewPayload(tokenParams)
+	if err != nil {
+		return "", payload, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", payload, err
+	}
+
+	return maker.sign(message), payload, nil
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	message, err := maker.verify(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return parsePasetoPayload(message)
+}
+
+func (maker *PasetoPublicMaker) sign(message []byte) string {
+	signature := ed25519.Sign(maker.privateKey, pae([]byte(pasetoPublicHeader), message, nil, nil))
+
+	body := append(append([]byte{}, message...), signature...)
+	return pasetoPublicHeader + b64.EncodeToString(body)
+}
+
+func (maker *PasetoPublicMaker) verify(token string) ([]byte, error) {
+	body, err := pasetoBody(token, pasetoPublicHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < ed25519.SignatureSize {
+		return nil, ErrInvalidToken
+	}
+
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(maker.publicKey, pae([]byte(pasetoPublicHeader), message, nil, nil), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	return message, nil
+}
+
+// pasetoBody returns the decoded body of a token without a footer, this
+// package never adds one
+func pasetoBody(token, header string) ([]byte, error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, ErrInvalidToken
+	}
+
+	body := token[len(header):]
+	if strings.Contains(body, ".") {
+		return nil, ErrInvalidToken
+	}
+
+	return b64.DecodeString(body)
+}
+
+func parsePasetoPayload(message []byte) (*Payload, error) {
+	var payload Payload
+	if err := json.Unmarshal(message, &payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
+
+// pae is the pre-authentication encoding of PASETO, it makes the
+// concatenation of the pieces unambiguous
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+
+	le64 := func(n int) {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(n)&(1<<63-1))
+		buf.Write(b[:])
+	}
+
+	le64(len(pieces))
+	for _, p := range pieces {
+		le64(len(p))
+		buf.Write(p)
+	}
+
+	return buf.Bytes()
+}
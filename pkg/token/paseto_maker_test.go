@@ -0,0 +1,212 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPasetoPublicMaker(t *testing.T) Maker {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	maker, err := NewPasetoPublicMaker(hex.EncodeToString(privateKey))
+	require.NoError(t, err)
+	return maker
+}
+
+func testMaker(t *testing.T, maker Maker) {
+	userID := utils.RandomInt(1, 10)
+	userEmail := faker.Email()
+	userType := "user"
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(&TokenParams{
+		UserID:   userID,
+		Email:    userEmail,
+		UserType: userType,
+		Duration: duration,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload2, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload2)
+
+	require.Equal(t, payload.ID, payload2.ID)
+	require.Equal(t, userID, payload2.UserID)
+	require.Equal(t, userEmail, payload2.Email)
+	require.WithinDuration(t, issuedAt, payload2.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload2.ExpiresAt, time.Second)
+	// revocation of all sessions compares issue times to the nanosecond
+	require.True(t, payload.IssuedAt.Equal(payload2.IssuedAt))
+}
+
+func testExpiredToken(t *testing.T, maker Maker) {
+	token, payload, err := maker.CreateToken(&TokenParams{
+		UserID:   utils.RandomInt(1, 10),
+		Email:    faker.Email(),
+		UserType: "user",
+		Duration: -time.Minute,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func testTamperedToken(t *testing.T, maker, other Maker) {
+	token, _, err := maker.CreateToken(&TokenParams{
+		UserID:   utils.RandomInt(1, 10),
+		Email:    faker.Email(),
+		UserType: "user",
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+
+	tampered := []string{
+		// a flipped bit in the body
+		token[:len(token)-10] + string(token[len(token)-10]^1) + token[len(token)-9:],
+		// cut off authentication tag or signature
+		token[:len(token)-4],
+		// a footer, which is never issued
+		token + ".Zm9vdGVy",
+		strings.Replace(token, "v4.", "v3.", 1),
+		"",
+	}
+	for _, tok := range tampered {
+		payload, err := maker.VerifyToken(tok)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+
+	// made with another key
+	payload, err := other.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoLocalMaker(t *testing.T) {
+	maker, err := NewPasetoLocalMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	testMaker(t, maker)
+}
+
+func TestExpiredPasetoLocalToken(t *testing.T) {
+	maker, err := NewPasetoLocalMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	testExpiredToken(t, maker)
+}
+
+func TestTamperedPasetoLocalToken(t *testing.T) {
+	maker, err := NewPasetoLocalMaker(utils.RandomString(32))
+	require.NoError(t, err)
+	other, err := NewPasetoLocalMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	testTamperedToken(t, maker, other)
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	testMaker(t, newTestPasetoPublicMaker(t))
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	testExpiredToken(t, newTestPasetoPublicMaker(t))
+}
+
+func TestTamperedPasetoPublicToken(t *testing.T) {
+	testTamperedToken(t, newTestPasetoPublicMaker(t), newTestPasetoPublicMaker(t))
+}
+
+func TestPasetoCrossPurpose(t *testing.T) {
+	local, err := NewPasetoLocalMaker(utils.RandomString(32))
+	require.NoError(t, err)
+	public := newTestPasetoPublicMaker(t)
+
+	token, _, err := public.CreateToken(&TokenParams{
+		UserID:   1,
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+	_, err = local.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	token, _, err = local.CreateToken(&TokenParams{
+		UserID:   1,
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+	_, err = public.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+// test vectors 4-E-1 and 4-S-1 of the PASETO specification
+func TestPasetoVectors(t *testing.T) {
+	key, err := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	require.NoError(t, err)
+	local := &PasetoLocalMaker{key}
+
+	message := `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`
+	token, err := local.encrypt([]byte(message), make([]byte, pasetoNonceSize))
+	require.NoError(t, err)
+	require.Equal(t, "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg", token)
+
+	decrypted, err := local.decrypt(token)
+	require.NoError(t, err)
+	require.Equal(t, message, string(decrypted))
+
+	maker, err := NewPasetoPublicMaker("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774")
+	require.NoError(t, err)
+	public := maker.(*PasetoPublicMaker)
+	require.Equal(t, "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2", hex.EncodeToString(public.publicKey))
+
+	message = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+	token = public.sign([]byte(message))
+	require.Equal(t, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA", token)
+
+	verified, err := public.verify(token)
+	require.NoError(t, err)
+	require.Equal(t, message, string(verified))
+}
+
+func TestNewMaker(t *testing.T) {
+	secret := utils.RandomString(32)
+
+	maker, err := NewMaker("", secret, "")
+	require.NoError(t, err)
+	require.IsType(t, &JWTMaker{}, maker)
+
+	maker, err = NewMaker(TypePasetoLocal, secret, "")
+	require.NoError(t, err)
+	require.IsType(t, &PasetoLocalMaker{}, maker)
+
+	_, err = NewMaker(TypePasetoLocal, "short", "")
+	require.Error(t, err)
+
+	_, err = NewMaker(TypePasetoPublic, secret, "not hex")
+	require.Error(t, err)
+	_, err = NewMaker(TypePasetoPublic, secret, "abcd")
+	require.Error(t, err)
+
+	_, err = NewMaker("paseto", secret, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package useragent
+
+import "strings"
+
+const (
+	BrowserEdge    = "Edge"
+	BrowserOpera   = "Opera"
+	BrowserChrome  = "Chrome"
+	BrowserFirefox = "Firefox"
+	BrowserSafari  = "Safari"
+	BrowserIE      = "Internet Explorer"
+	BrowserBot     = "Bot"
+	BrowserOther   = "Other"
+)
+
+const (
+	OSIOS      = "iOS"
+	OSAndroid  = "Android"
+	OSWindows  = "Windows"
+	OSMacOS    = "macOS"
+	OSChromeOS = "ChromeOS"
+	OSLinux    = "Linux"
+	OSOther    = "Other"
+)
+
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+var botMarkers = []string{"bot", "crawler", "spider", "curl", "wget", "python-requests", "go-http-client"}
+
+// previewMarkers are agents of chat apps and social networks which fetch
+// links to show a preview of them
+var previewMarkers = []string{
+	"facebookexternalhit", "facebookcatalog", "facebot", "twitterbot", "linkedinbot",
+	"slackbot", "slack-imgproxy", "discordbot", "telegrambot", "whatsapp", "skypeuripreview",
+	"pinterest", "redditbot", "vkshare", "embedly", "iframely", "mastodon",
+}
+
+// Browser returns the browser family of the given User-Agent header.
+// The order of the checks matters: Edge and Opera also announce
+// themselves as Chrome, and Chrome also announces itself as Safari.
+func Browser(ua string) string {
+	s := strings.ToLower(ua)
+
+	switch {
+	case s == "":
+		return BrowserOther
+	case containsAny(s, botMarkers...):
+		return BrowserBot
+	case containsAny(s, "edg/", "edge/", "edga/", "edgios/"):
+		return BrowserEdge
+	case containsAny(s, "opr/", "opera"):
+		return BrowserOpera
+	case containsAny(s, "chrome/", "crios/", "chromium/"):
+		return BrowserChrome
+	case containsAny(s, "firefox/", "fxios/"):
+		return BrowserFirefox
+	case strings.Contains(s, "safari/"):
+		return BrowserSafari
+	case containsAny(s, "msie ", "trident/"):
+		return BrowserIE
+	}
+
+	return BrowserOther
+}
+
+// OS returns the operating system family of the given User-Agent header.
+// iOS and Android are checked first, because their agents also mention
+// "Mac OS X" and "Linux".
+func OS(ua string) string {
+	s := strings.ToLower(ua)
+
+	switch {
+	case containsAny(s, "iphone", "ipad", "ipod"):
+		return OSIOS
+	case strings.Contains(s, "android"):
+		return OSAndroid
+	case strings.Contains(s, "windows"):
+		return OSWindows
+	case strings.Contains(s, "cros"):
+		return OSChromeOS
+	case containsAny(s, "macintosh", "mac os x"):
+		return OSMacOS
+	case strings.Contains(s, "linux"):
+		return OSLinux
+	}
+
+	return OSOther
+}
+
+// Device returns the device class of the given User-Agent header, agents
+// which can not be classified are treated as desktops
+func Device(ua string) string {
+	s := strings.ToLower(ua)
+
+	switch {
+	case Browser(ua) == BrowserBot:
+		return DeviceBot
+	case containsAny(s, "ipad", "tablet"),
+		strings.Contains(s, "android") && !strings.Contains(s, "mobile"):
+		return DeviceTablet
+	case containsAny(s, "mobile", "iphone", "ipod"):
+		return DeviceMobile
+	}
+
+	return DeviceDesktop
+}
+
+// IsPreviewCrawler reports whether the agent fetches links to build a
+// social or chat preview
+func IsPreviewCrawler(ua string) bool {
+	return containsAny(strings.ToLower(ua), previewMarkers...)
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
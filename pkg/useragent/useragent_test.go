@@ -0,0 +1,66 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowser(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36":                         BrowserChrome,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36 Edg/110.0.1587.57":       BrowserEdge,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36 OPR/96.0.0.0":            BrowserOpera,
+		"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/110.0":                                                                  BrowserFirefox,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1": BrowserSafari,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                                                                BrowserBot,
+		"curl/7.88.1": BrowserBot,
+		"":            BrowserOther,
+	}
+
+	for ua, expected := range cases {
+		require.Equal(t, expected, Browser(ua), ua)
+	}
+}
+
+func TestOSAndDevice(t *testing.T) {
+	cases := []struct {
+		ua     string
+		os     string
+		device string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1", OSIOS, DeviceMobile},
+		{"Mozilla/5.0 (iPad; CPU OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1", OSIOS, DeviceTablet},
+		{"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36", OSAndroid, DeviceMobile},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36", OSAndroid, DeviceTablet},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36", OSWindows, DeviceDesktop},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 13_2_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Safari/605.1.15", OSMacOS, DeviceDesktop},
+		{"Mozilla/5.0 (X11; CrOS x86_64 15329.44.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36", OSChromeOS, DeviceDesktop},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/110.0", OSLinux, DeviceDesktop},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", OSOther, DeviceBot},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.os, OS(c.ua), c.ua)
+		require.Equal(t, c.device, Device(c.ua), c.ua)
+	}
+}
+
+func TestIsPreviewCrawler(t *testing.T) {
+	cases := map[string]bool{
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)": true,
+		"Twitterbot/1.0": true,
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)":                                                      true,
+		"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)":                                               true,
+		"TelegramBot (like TwitterBot)":                                                                                   true,
+		"WhatsApp/2.23.2.72 A":                                                                                            true,
+		"LinkedInBot/1.0 (compatible; Mozilla/5.0; Apache-HttpClient +http://www.linkedin.com)":                           true,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                                        false,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36": false,
+		"": false,
+	}
+
+	for ua, expected := range cases {
+		require.Equal(t, expected, IsPreviewCrawler(ua), ua)
+	}
+}
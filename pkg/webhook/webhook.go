@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	EventUrlCreated   = "url.created"
+	EventUrlUpdated   = "url.updated"
+	EventUrlDeleted   = "url.deleted"
+	EventUrlExpired   = "url.expired"
+	EventUrlExhausted = "url.exhausted"
+	// EventUrlClicks is sent once the clicks of a url reach one of
+	// ClickThresholds
+	EventUrlClicks = "url.clicks"
+
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	// signaturePrefix tells receivers which algorithm signed the body
+	signaturePrefix = "sha256="
+
+	firstRetry = 30 * time.Second
+	maxRetry   = 12 * time.Hour
+)
+
+var Events = []string{
+	EventUrlCreated,
+	EventUrlUpdated,
+	EventUrlDeleted,
+	EventUrlExpired,
+	EventUrlExhausted,
+	EventUrlClicks,
+}
+
+var ClickThresholds = []int64{10, 100, 1000, 10000, 100000, 1000000}
+
+func IsEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClickThreshold reports whether clicks is one of ClickThresholds
+func IsClickThreshold(clicks int64) bool {
+	for _, t := range ClickThresholds {
+		if t == clicks {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the json body of every delivery
+type Payload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      *Data     `json:"data"`
+}
+
+type Data struct {
+	Url *Url `json:"url"`
+	// Clicks is the reached threshold of EventUrlClicks
+	Clicks int64 `json:"clicks,omitempty"`
+}
+
+// Url is the link as receivers see it
+type Url struct {
+	Id             int64      `json:"id"`
+	UserId         int64      `json:"user_id"`
+	OriginalUrl    string     `json:"original_url"`
+	HashedUrl      string     `json:"hashed_url"`
+	ShortUrl       string     `json:"short_url"`
+	DomainId       int64      `json:"domain_id"`
+	FolderId       int64      `json:"folder_id"`
+	TagIds         []int64    `json:"tag_ids"`
+	MaxClicks      *int64     `json:"max_clicks"`
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Active         bool       `json:"active"`
+	InactiveReason string     `json:"inactive_reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of
+// "timestamp.body" keyed with the webhook secret. The timestamp is part
+// of it so receivers can reject replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature made by Sign in constant time
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff returns how long to wait after the given number of failed
+// attempts, the wait doubles with every attempt up to 12 hours
+func Backoff(attempts int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxRetry {
+			return maxRetry
+		}
+	}
+	return wait
+}
+
+// NewSecret returns a random secret for signing deliveries
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
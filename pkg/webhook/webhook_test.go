@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"url.created"}`)
+
+	// reference value from: printf '1700000000.{"event":"url.created"}' | openssl dgst -sha256 -hmac secret
+	signature := Sign("secret", 1700000000, body)
+	require.Equal(t, "sha256=bbda9a7f5b6c44499f6360d4b20d1aa66adaac82b25fb97c94299c04cf0c0a8b", signature)
+
+	require.True(t, Verify("secret", 1700000000, body, signature))
+	require.False(t, Verify("other", 1700000000, body, signature))
+	require.False(t, Verify("secret", 1700000001, body, signature))
+	require.False(t, Verify("secret", 1700000000, []byte(`{"event":"url.deleted"}`), signature))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1))
+	require.Equal(t, time.Minute, Backoff(2))
+	require.Equal(t, 4*time.Minute, Backoff(4))
+	require.Equal(t, 12*time.Hour, Backoff(20))
+	require.Equal(t, 12*time.Hour, Backoff(1000))
+}
+
+func TestEvents(t *testing.T) {
+	require.True(t, IsEvent(EventUrlCreated))
+	require.False(t, IsEvent("url.*"))
+
+	require.False(t, IsClickThreshold(9))
+	require.True(t, IsClickThreshold(10))
+	require.False(t, IsClickThreshold(101))
+	require.True(t, IsClickThreshold(1000000))
+	require.False(t, IsClickThreshold(5000000))
+
+	s1, err := NewSecret()
+	require.NoError(t, err)
+	s2, err := NewSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, s1, s2)
+}
@@ -2,14 +2,33 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// UrlCachePrefix changes with the format of cached urls, so entries of
+// the old format are never read
+const UrlCachePrefix = "redirect_url_"
+
+// UrlCacheKey is the key urls are cached under for the redirect path
+func UrlCacheKey(domainID int64, slug string) string {
+	return fmt.Sprintf("%s%d_%s", UrlCachePrefix, domainID, slug)
+}
+
 type InMemoryStorageI interface {
 	Set(key, value string, exp time.Duration) error
 	Get(key string) (string, error)
+	Del(keys ...string) error
+	// Exists reports whether the key is set, unlike Get it tells a missing
+	// key apart from a failure
+	Exists(key string) (bool, error)
+	// Incr increments the counter and returns its new value, the expiration
+	// is set when the counter is created
+	Incr(key string, exp time.Duration) (int64, error)
+	// IncrBy is Incr by n
+	IncrBy(key string, n int64, exp time.Duration) (int64, error)
 }
 
 type storageRedis struct {
@@ -40,3 +59,37 @@ func (rd *storageRedis) Get(key string) (string, error) {
 	}
 	return val, nil
 }
+
+func (rd *storageRedis) Del(keys ...string) error {
+	return rd.client.Del(context.Background(), keys...).Err()
+}
+
+func (rd *storageRedis) Exists(key string) (bool, error) {
+	count, err := rd.client.Exists(context.Background(), key).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Incr creates the counter with its expiration and increments it in one
+// transaction, a counter must never be left without an expiration
+func (rd *storageRedis) Incr(key string, exp time.Duration) (int64, error) {
+	return rd.IncrBy(key, 1, exp)
+}
+
+func (rd *storageRedis) IncrBy(key string, n int64, exp time.Duration) (int64, error) {
+	ctx := context.Background()
+
+	var incr *redis.IntCmd
+	_, err := rd.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, key, 0, exp)
+		incr = pipe.IncrBy(ctx, key, n)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type apiKeyRepo struct {
+	db *sqlx.DB
+}
+
+func NewApiKey(db *sqlx.DB) repo.ApiKeyStorageI {
+	return &apiKeyRepo{
+		db: db,
+	}
+}
+
+const apiKeyColumns = `
+	id,
+	user_id,
+	name,
+	prefix,
+	key_hash,
+	scopes,
+	expires_at,
+	last_used_at,
+	created_at
+`
+
+func scanApiKey(row rowScanner) (*repo.ApiKey, error) {
+	var result repo.ApiKey
+
+	err := row.Scan(
+		&result.Id,
+		&result.UserId,
+		&result.Name,
+		&result.Prefix,
+		&result.Hash,
+		pq.Array(&result.Scopes),
+		&result.ExpiresAt,
+		&result.LastUsedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (ar *apiKeyRepo) Create(key *repo.ApiKey) (*repo.ApiKey, error) {
+	query := `
+		insert into api_keys(
+			user_id,
+			name,
+			prefix,
+			key_hash,
+			scopes,
+			expires_at
+		) values ($1, $2, $3, $4, $5, $6)
+		returning id, created_at
+	`
+
+	err := ar.db.QueryRow(
+		query,
+		key.UserId,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+	).Scan(
+		&key.Id,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetByHash hides keys of deleted users, they must not authenticate
+func (ar *apiKeyRepo) GetByHash(hash string) (*repo.ApiKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + ` FROM api_keys
+		WHERE key_hash=$1 AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+	`
+
+	return scanApiKey(ar.db.QueryRow(query, hash))
+}
+
+func (ar *apiKeyRepo) GetAll(userID int64) ([]*repo.ApiKey, error) {
+	result := make([]*repo.ApiKey, 0)
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id=$1 ORDER BY id`
+	rows, err := ar.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, key)
+	}
+
+	return result, rows.Err()
+}
+
+func (ar *apiKeyRepo) Delete(id, userID int64) error {
+	query := ` delete from api_keys where id=$1 and user_id=$2 `
+
+	res, err := ar.db.Exec(
+		query,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ar *apiKeyRepo) Touch(id int64) error {
+	query := `
+		update api_keys set last_used_at=now()
+		where id=$1 and (last_used_at is null or last_used_at < now() - interval '1 minute')
+	`
+
+	_, err := ar.db.Exec(query, id)
+	return err
+}
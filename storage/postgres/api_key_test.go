@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKey(t *testing.T) {
+	user := createUser(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	key, err := strg.ApiKey().Create(&repo.ApiKey{
+		UserId:    user.Id,
+		Name:      faker.Word(),
+		Prefix:    "lk_0123abcd",
+		Hash:      faker.UUIDDigit(),
+		Scopes:    []string{"links:read", "stats:read"},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, key.Id)
+
+	key2, err := strg.ApiKey().GetByHash(key.Hash)
+	require.NoError(t, err)
+	require.Equal(t, key.Id, key2.Id)
+	require.Equal(t, key.Scopes, key2.Scopes)
+	require.NotNil(t, key2.ExpiresAt)
+	require.Nil(t, key2.LastUsedAt)
+
+	err = strg.ApiKey().Touch(key.Id)
+	require.NoError(t, err)
+
+	keys, err := strg.ApiKey().GetAll(user.Id)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	// keys of deleted users do not authenticate
+	err = strg.User().Delete(user.Id)
+	require.NoError(t, err)
+	_, err = strg.ApiKey().GetByHash(key.Hash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = strg.User().Restore(user.Id, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = strg.ApiKey().GetByHash(key.Hash)
+	require.NoError(t, err)
+
+	err = strg.ApiKey().Delete(key.Id, user.Id+1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = strg.ApiKey().Delete(key.Id, user.Id)
+	require.NoError(t, err)
+	_, err = strg.ApiKey().GetByHash(key.Hash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, user.Id)
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type clickRepo struct {
+	db *sqlx.DB
+}
+
+func NewClick(db *sqlx.DB) repo.ClickStorageI {
+	return &clickRepo{
+		db: db,
+	}
+}
+
+func (cr *clickRepo) Create(click *repo.Click) (*repo.Click, error) {
+	// the counter is updated in the same statement, concurrent clicks of
+	// the url wait for each other and get consecutive counts
+	query := `
+		WITH click AS (
+			insert into url_clicks(
+				url_id,
+				referrer,
+				user_agent,
+				ip_hash,
+				accept_language,
+				destination_id
+			) values ($1, $2, $3, $4, $5, $6)
+			returning id, url_id, clicked_at
+		)
+		UPDATE urls SET click_count = urls.click_count + 1
+		FROM click
+		WHERE urls.id = click.url_id
+		RETURNING click.id, click.clicked_at, urls.click_count
+	`
+
+	err := cr.db.QueryRow(
+		query,
+		click.UrlId,
+		utils.NullString(click.Referrer),
+		utils.NullString(click.UserAgent),
+		utils.NullString(click.IpHash),
+		utils.NullString(click.AcceptLanguage),
+		utils.NullInt64(click.DestinationId),
+	).Scan(
+		&click.Id,
+		&click.ClickedAt,
+		&click.UrlClicks,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return click, nil
+}
+
+func (cr *clickRepo) GetStats(params *repo.GetClickStatsParams) (*repo.ClickStats, error) {
+	result := repo.ClickStats{
+		Series:       make([]*repo.ClickSeriesPoint, 0),
+		Referrers:    make([]*repo.ClickCount, 0),
+		UserAgents:   make([]*repo.ClickCount, 0),
+		Destinations: make([]*repo.DestinationClicks, 0),
+	}
+
+	filter := ` WHERE url_id=$1 AND clicked_at >= $2 AND clicked_at < $3 `
+
+	queryTotal := `
+		SELECT
+			count(1),
+			count(DISTINCT ip_hash)
+		FROM url_clicks
+	` + filter
+	err := cr.db.QueryRow(queryTotal, params.UrlID, params.From, params.To).Scan(
+		&result.TotalClicks,
+		&result.UniqueVisitors,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	querySeries := `
+		SELECT
+			date_trunc($4, clicked_at) AS bucket,
+			count(1)
+		FROM url_clicks
+		` + filter + `
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+	rows, err := cr.db.Query(querySeries, params.UrlID, params.From, params.To, params.Interval)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p repo.ClickSeriesPoint
+		err := rows.Scan(
+			&p.Bucket,
+			&p.Clicks,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Series = append(result.Series, &p)
+	}
+
+	result.Referrers, err = cr.countBy("referrer", filter, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result.UserAgents, err = cr.countBy("user_agent", filter, params)
+	if err != nil {
+		return nil, err
+	}
+
+	queryDestinations := `
+		SELECT
+			destination_id,
+			count(1),
+			count(DISTINCT ip_hash)
+		FROM url_clicks
+		` + filter + ` AND destination_id IS NOT NULL
+		GROUP BY destination_id
+		ORDER BY destination_id
+	`
+	rows, err = cr.db.Query(queryDestinations, params.UrlID, params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d repo.DestinationClicks
+		err := rows.Scan(
+			&d.DestinationId,
+			&d.Clicks,
+			&d.UniqueVisitors,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Destinations = append(result.Destinations, &d)
+	}
+
+	return &result, nil
+}
+
+// countBy groups clicks by one of the url_clicks columns. column is never
+// user input, it is always one of the constants passed by GetStats.
+func (cr *clickRepo) countBy(column, filter string, params *repo.GetClickStatsParams) ([]*repo.ClickCount, error) {
+	result := make([]*repo.ClickCount, 0)
+
+	query := `
+		SELECT
+			coalesce(` + column + `, ''),
+			count(1) AS clicks
+		FROM url_clicks
+		` + filter + `
+		GROUP BY 1
+		ORDER BY clicks desc
+	`
+	rows, err := cr.db.Query(query, params.UrlID, params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c repo.ClickCount
+		err := rows.Scan(
+			&c.Value,
+			&c.Clicks,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &c)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createClick(t *testing.T, urlID int64) *repo.Click {
+	c := repo.Click{
+		UrlId:          urlID,
+		Referrer:       faker.URL(),
+		UserAgent:      "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/110.0",
+		IpHash:         faker.UUIDDigit(),
+		AcceptLanguage: "en-US,en;q=0.9",
+	}
+	click, err := strg.Click().Create(&c)
+	require.NoError(t, err)
+	require.NotZero(t, click.Id)
+	require.NotZero(t, click.ClickedAt)
+
+	return click
+}
+
+func TestCreateClick(t *testing.T) {
+	url := createUrl(t)
+	require.Equal(t, int64(1), createClick(t, url.Id).UrlClicks)
+	require.Equal(t, int64(2), createClick(t, url.Id).UrlClicks)
+	deleteUser(t, url.UserId)
+}
+
+func TestGetClickStats(t *testing.T) {
+	url := createUrl(t)
+	for i := 0; i < 5; i++ {
+		createClick(t, url.Id)
+	}
+
+	stats, err := strg.Click().GetStats(&repo.GetClickStatsParams{
+		UrlID:    url.Id,
+		Interval: "hour",
+		From:     time.Now().Add(-time.Hour),
+		To:       time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), stats.TotalClicks)
+	require.Equal(t, int64(5), stats.UniqueVisitors)
+	require.NotEmpty(t, stats.Series)
+	require.Len(t, stats.Referrers, 5)
+	require.Len(t, stats.UserAgents, 1)
+	deleteUser(t, url.UserId)
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type destinationRepo struct {
+	db *sqlx.DB
+}
+
+func NewDestination(db *sqlx.DB) repo.DestinationStorageI {
+	return &destinationRepo{
+		db: db,
+	}
+}
+
+func (dr *destinationRepo) GetAll(urlID int64) ([]*repo.Destination, error) {
+	result := make([]*repo.Destination, 0)
+
+	query := `
+		SELECT
+			id,
+			url_id,
+			target_url,
+			weight,
+			created_at
+		FROM url_destinations
+		WHERE url_id=$1
+		ORDER BY id
+	`
+	rows, err := dr.db.Query(query, urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d repo.Destination
+		err := rows.Scan(
+			&d.Id,
+			&d.UrlId,
+			&d.TargetUrl,
+			&d.Weight,
+			&d.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &d)
+	}
+
+	return result, nil
+}
+
+func (dr *destinationRepo) Set(urlID int64, sticky bool, destinations []*repo.Destination) ([]*repo.Destination, error) {
+	tx, err := dr.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(` UPDATE urls SET sticky_destinations=$1 WHERE id=$2 `, sticky, urlID)
+	if err != nil {
+		return nil, err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	keep := make([]int64, 0, len(destinations))
+	for _, d := range destinations {
+		if d.Id != 0 {
+			keep = append(keep, d.Id)
+		}
+	}
+	_, err = tx.Exec(
+		` DELETE FROM url_destinations WHERE url_id=$1 AND NOT (id = ANY($2)) `,
+		urlID,
+		pq.Array(keep),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range destinations {
+		d.UrlId = urlID
+		if d.Id == 0 {
+			err = tx.QueryRow(`
+				insert into url_destinations(
+					url_id,
+					target_url,
+					weight
+				) values ($1, $2, $3)
+				returning id, created_at
+			`, urlID, d.TargetUrl, d.Weight).Scan(&d.Id, &d.CreatedAt)
+		} else {
+			err = tx.QueryRow(`
+				update url_destinations set
+					target_url=$1,
+					weight=$2
+				where id=$3 and url_id=$4
+				returning created_at
+			`, d.TargetUrl, d.Weight, d.Id, urlID).Scan(&d.CreatedAt)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return destinations, nil
+}
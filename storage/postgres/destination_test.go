@@ -0,0 +1,56 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDestinations(t *testing.T) {
+	url := createUrl(t)
+
+	destinations, err := strg.Destination().Set(url.Id, true, []*repo.Destination{
+		{TargetUrl: faker.URL(), Weight: 70},
+		{TargetUrl: faker.URL(), Weight: 30},
+	})
+	require.NoError(t, err)
+	require.Len(t, destinations, 2)
+	require.NotZero(t, destinations[0].Id)
+
+	url2, err := strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.True(t, url2.StickyDestinations)
+
+	// the first one is kept and updated, the second one is replaced
+	destinations[0].Weight = 50
+	_, err = strg.Destination().Set(url.Id, false, []*repo.Destination{
+		destinations[0],
+		{TargetUrl: faker.URL(), Weight: 50},
+	})
+	require.NoError(t, err)
+
+	destinations2, err := strg.Destination().GetAll(url.Id)
+	require.NoError(t, err)
+	require.Len(t, destinations2, 2)
+	require.Equal(t, destinations[0].Id, destinations2[0].Id)
+	require.Equal(t, 50, destinations2[0].Weight)
+	require.NotEqual(t, destinations[1].Id, destinations2[1].Id)
+
+	click, err := strg.Click().Create(&repo.Click{
+		UrlId:         url.Id,
+		IpHash:        faker.UUIDDigit(),
+		DestinationId: destinations2[1].Id,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, click.Id)
+
+	_, err = strg.Destination().Set(url.Id, false, nil)
+	require.NoError(t, err)
+
+	destinations3, err := strg.Destination().GetAll(url.Id)
+	require.NoError(t, err)
+	require.Empty(t, destinations3)
+	deleteUser(t, url.UserId)
+}
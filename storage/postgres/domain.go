@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type domainRepo struct {
+	db *sqlx.DB
+}
+
+func NewDomain(db *sqlx.DB) repo.DomainStorageI {
+	return &domainRepo{
+		db: db,
+	}
+}
+
+func (dr *domainRepo) Create(domain *repo.Domain) (*repo.Domain, error) {
+	query := `
+		insert into domains(
+			user_id,
+			host,
+			verification_token
+		) values ($1, $2, $3)
+		returning id, created_at
+	`
+
+	err := dr.db.QueryRow(
+		query,
+		domain.UserId,
+		domain.Host,
+		domain.VerificationToken,
+	).Scan(
+		&domain.Id,
+		&domain.CreatedAt,
+	)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	return domain, nil
+}
+
+func (dr *domainRepo) Get(id int64) (*repo.Domain, error) {
+	var result repo.Domain
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			host,
+			verification_token,
+			verified_at,
+			created_at
+		FROM domains
+		WHERE id=$1
+	`
+
+	err := dr.db.QueryRow(query, id).Scan(
+		&result.Id,
+		&result.UserId,
+		&result.Host,
+		&result.VerificationToken,
+		&result.VerifiedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (dr *domainRepo) GetByHost(host string) (*repo.Domain, error) {
+	var result repo.Domain
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			host,
+			verification_token,
+			verified_at,
+			created_at
+		FROM domains
+		WHERE host=$1 AND verified_at IS NOT NULL
+	`
+
+	err := dr.db.QueryRow(query, host).Scan(
+		&result.Id,
+		&result.UserId,
+		&result.Host,
+		&result.VerificationToken,
+		&result.VerifiedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (dr *domainRepo) GetAll(userID int64) ([]*repo.Domain, error) {
+	result := make([]*repo.Domain, 0)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			host,
+			verification_token,
+			verified_at,
+			created_at
+		FROM domains
+		WHERE user_id=$1
+		ORDER BY created_at desc
+	`
+	rows, err := dr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d repo.Domain
+		err := rows.Scan(
+			&d.Id,
+			&d.UserId,
+			&d.Host,
+			&d.VerificationToken,
+			&d.VerifiedAt,
+			&d.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &d)
+	}
+
+	return result, nil
+}
+
+func (dr *domainRepo) Verify(id int64) (*repo.Domain, error) {
+	query := `
+		update domains set verified_at=current_timestamp
+		where id=$1 and verified_at is null
+	`
+
+	_, err := dr.db.Exec(query, id)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	return dr.Get(id)
+}
+
+func (dr *domainRepo) Delete(id, userID int64) error {
+	query := ` delete from domains where id=$1 and user_id=$2 `
+
+	res, err := dr.db.Exec(
+		query,
+		id,
+		userID,
+	)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return repo.ErrInUse
+	} else if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
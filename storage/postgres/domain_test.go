@@ -0,0 +1,152 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createDomain(t *testing.T, userID int64) *repo.Domain {
+	d := repo.Domain{
+		UserId: userID,
+		Host:              faker.DomainName(),
+		VerificationToken: utils.RandomString(32),
+	}
+	domain, err := strg.Domain().Create(&d)
+	require.NoError(t, err)
+	require.NotZero(t, domain.Id)
+	require.NotZero(t, domain.CreatedAt)
+	require.Nil(t, domain.VerifiedAt)
+
+	return domain
+}
+
+func TestCreateDomain(t *testing.T) {
+	user := createUser(t)
+	createDomain(t, user.Id)
+	deleteUser(t, user.Id)
+}
+
+func TestGetDomainByHost(t *testing.T) {
+	user := createUser(t)
+	domain := createDomain(t, user.Id)
+
+	// unverified domains are not resolved
+	_, err := strg.Domain().GetByHost(domain.Host)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = strg.Domain().Verify(domain.Id)
+	require.NoError(t, err)
+	domain2, err := strg.Domain().GetByHost(domain.Host)
+	require.NoError(t, err)
+	require.Equal(t, domain.Id, domain2.Id)
+	require.Equal(t, domain.UserId, domain2.UserId)
+
+	_, err = strg.Domain().GetByHost(faker.DomainName())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, user.Id)
+}
+
+func TestVerifyDomain(t *testing.T) {
+	user := createUser(t)
+	user2 := createUser(t)
+	domain := createDomain(t, user.Id)
+
+	// other users may claim the host until it is verified
+	domain2, err := strg.Domain().Create(&repo.Domain{
+		UserId: user2.Id,
+		Host:   domain.Host,
+	})
+	require.NoError(t, err)
+	_, err = strg.Domain().Create(&repo.Domain{
+		UserId: user.Id,
+		Host:   domain.Host,
+	})
+	require.ErrorIs(t, err, repo.ErrAlreadyExists)
+
+	verified, err := strg.Domain().Verify(domain.Id)
+	require.NoError(t, err)
+	require.NotNil(t, verified.VerifiedAt)
+	require.Equal(t, domain.VerificationToken, verified.VerificationToken)
+
+	// verifying again keeps the first time
+	verified2, err := strg.Domain().Verify(domain.Id)
+	require.NoError(t, err)
+	require.Equal(t, verified.VerifiedAt, verified2.VerifiedAt)
+
+	_, err = strg.Domain().Verify(domain2.Id)
+	require.ErrorIs(t, err, repo.ErrAlreadyExists)
+	deleteUser(t, user.Id)
+	deleteUser(t, user2.Id)
+}
+
+func TestGetAllDomains(t *testing.T) {
+	user := createUser(t)
+	for i := 0; i < 3; i++ {
+		createDomain(t, user.Id)
+	}
+
+	domains, err := strg.Domain().GetAll(user.Id)
+	require.NoError(t, err)
+	require.Len(t, domains, 3)
+	deleteUser(t, user.Id)
+}
+
+func TestDeleteDomain(t *testing.T) {
+	user := createUser(t)
+	domain := createDomain(t, user.Id)
+
+	err := strg.Domain().Delete(domain.Id, -1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = strg.Domain().Delete(domain.Id, user.Id)
+	require.NoError(t, err)
+	deleteUser(t, user.Id)
+}
+
+func TestDeleteDomainInUse(t *testing.T) {
+	user := createUser(t)
+	domain := createDomain(t, user.Id)
+	url, err := strg.Url().Create(&repo.Url{
+		UserId:      user.Id,
+		OriginalUrl: faker.URL(),
+		HashedUrl:   faker.Username(),
+		DomainId:    domain.Id,
+	})
+	require.NoError(t, err)
+
+	// links in the trash keep the domain as well
+	err = strg.Url().Delete(url.Id, user.Id)
+	require.NoError(t, err)
+	err = strg.Domain().Delete(domain.Id, user.Id)
+	require.ErrorIs(t, err, repo.ErrInUse)
+	deleteUser(t, user.Id)
+}
+
+func TestGetUrlByDomain(t *testing.T) {
+	url := createUrl(t)
+	domain := createDomain(t, url.UserId)
+	click := int64(10)
+	url2, err := strg.Url().Create(&repo.Url{
+		UserId:      url.UserId,
+		OriginalUrl: faker.URL(),
+		HashedUrl:   url.HashedUrl,
+		MaxClicks:   &click,
+		DomainId:    domain.Id,
+	})
+	require.NoError(t, err)
+
+	url3, err := strg.Url().Get(url.HashedUrl, domain.Id)
+	require.NoError(t, err)
+	require.Equal(t, url2.Id, url3.Id)
+	require.Equal(t, domain.Id, url3.DomainId)
+
+	url4, err := strg.Url().Get(url.HashedUrl, 0)
+	require.NoError(t, err)
+	require.Equal(t, url.Id, url4.Id)
+	require.Zero(t, url4.DomainId)
+	deleteUser(t, url.UserId)
+}
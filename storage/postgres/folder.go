@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type folderRepo struct {
+	db *sqlx.DB
+}
+
+func NewFolder(db *sqlx.DB) repo.FolderStorageI {
+	return &folderRepo{
+		db: db,
+	}
+}
+
+func (fr *folderRepo) Create(folder *repo.Folder) (*repo.Folder, error) {
+	query := `
+		insert into folders(
+			user_id,
+			name
+		) values ($1, $2)
+		returning id, created_at
+	`
+
+	err := fr.db.QueryRow(
+		query,
+		folder.UserId,
+		folder.Name,
+	).Scan(
+		&folder.Id,
+		&folder.CreatedAt,
+	)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	return folder, nil
+}
+
+func (fr *folderRepo) Get(id int64) (*repo.Folder, error) {
+	var result repo.Folder
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			created_at
+		FROM folders
+		WHERE id=$1
+	`
+
+	err := fr.db.QueryRow(query, id).Scan(
+		&result.Id,
+		&result.UserId,
+		&result.Name,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (fr *folderRepo) GetAll(userID int64) ([]*repo.Folder, error) {
+	result := make([]*repo.Folder, 0)
+
+	query := `
+		SELECT
+			f.id,
+			f.user_id,
+			f.name,
+			f.created_at,
+			count(u.id)
+		FROM folders f
+		LEFT JOIN urls u ON u.folder_id = f.id AND u.deleted_at IS NULL
+		WHERE f.user_id=$1
+		GROUP BY f.id
+		ORDER BY f.name
+	`
+	rows, err := fr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f repo.Folder
+		err := rows.Scan(
+			&f.Id,
+			&f.UserId,
+			&f.Name,
+			&f.CreatedAt,
+			&f.Urls,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &f)
+	}
+
+	return result, nil
+}
+
+func (fr *folderRepo) Update(folder *repo.Folder) (*repo.Folder, error) {
+	query := `
+		update folders set name=$1
+		where id=$2 and user_id=$3
+		returning created_at
+	`
+
+	err := fr.db.QueryRow(
+		query,
+		folder.Name,
+		folder.Id,
+		folder.UserId,
+	).Scan(
+		&folder.CreatedAt,
+	)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	return folder, nil
+}
+
+func (fr *folderRepo) Delete(id, userID int64) error {
+	query := ` delete from folders where id=$1 and user_id=$2 `
+
+	res, err := fr.db.Exec(
+		query,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createFolder(t *testing.T, userID int64) *repo.Folder {
+	folder, err := strg.Folder().Create(&repo.Folder{
+		UserId: userID,
+		Name:   faker.Word() + faker.UUIDDigit(),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, folder.Id)
+	require.NotZero(t, folder.CreatedAt)
+
+	return folder
+}
+
+func TestCreateFolder(t *testing.T) {
+	user := createUser(t)
+	folder := createFolder(t, user.Id)
+
+	_, err := strg.Folder().Create(&repo.Folder{
+		UserId: user.Id,
+		Name:   folder.Name,
+	})
+	require.ErrorIs(t, err, repo.ErrAlreadyExists)
+	deleteUser(t, user.Id)
+}
+
+func TestGetAllFolders(t *testing.T) {
+	url := createUrl(t)
+	folder := createFolder(t, url.UserId)
+	createFolder(t, url.UserId)
+
+	url.FolderId = folder.Id
+	_, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+
+	folders, err := strg.Folder().GetAll(url.UserId)
+	require.NoError(t, err)
+	require.Len(t, folders, 2)
+	for _, f := range folders {
+		if f.Id == folder.Id {
+			require.Equal(t, int64(1), f.Urls)
+		} else {
+			require.Zero(t, f.Urls)
+		}
+	}
+
+	result, err := strg.Url().GetAll(&repo.GetAllUrlsParams{
+		Limit:    10,
+		Page:     1,
+		UserID:   url.UserId,
+		FolderID: folder.Id,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Urls, 1)
+	require.Equal(t, folder.Id, result.Urls[0].FolderId)
+	deleteUser(t, url.UserId)
+}
+
+func TestDeleteFolder(t *testing.T) {
+	url := createUrl(t)
+	folder := createFolder(t, url.UserId)
+
+	url.FolderId = folder.Id
+	_, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+
+	err = strg.Folder().Delete(folder.Id, -1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = strg.Folder().Delete(folder.Id, url.UserId)
+	require.NoError(t, err)
+
+	url2, err := strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.Zero(t, url2.FolderId)
+	deleteUser(t, url.UserId)
+}
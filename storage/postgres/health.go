@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type healthRepo struct {
+	db *sqlx.DB
+}
+
+func NewHealth(db *sqlx.DB) repo.HealthStorageI {
+	return &healthRepo{
+		db: db,
+	}
+}
+
+func (hr *healthRepo) GetDue(checkedBefore time.Time, limit int) ([]*repo.Url, error) {
+	result := make([]*repo.Url, 0)
+
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		LEFT JOIN url_health h ON h.url_id = urls.id
+		WHERE is_active AND deleted_at IS NULL
+			AND (starts_at IS NULL OR starts_at <= now())
+			AND (h.checked_at IS NULL OR h.checked_at < $1)
+		ORDER BY h.checked_at NULLS FIRST, urls.id
+		LIMIT $2
+	`
+	rows, err := hr.db.Query(query, checkedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanUrl(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	return result, rows.Err()
+}
+
+func (hr *healthRepo) Save(h *repo.UrlHealth) (*repo.UrlHealth, error) {
+	query := `
+		insert into url_health(
+			url_id,
+			status_code,
+			latency_ms,
+			final_url,
+			error,
+			consecutive_failures,
+			broken_since,
+			checked_at
+		) values ($1, $2, $3, $4, $5, CASE WHEN $6 THEN 1 ELSE 0 END, CASE WHEN $6 THEN now() END, now())
+		on conflict (url_id) do update set
+			status_code=EXCLUDED.status_code,
+			latency_ms=EXCLUDED.latency_ms,
+			final_url=EXCLUDED.final_url,
+			error=EXCLUDED.error,
+			consecutive_failures=CASE WHEN $6 THEN url_health.consecutive_failures + 1 ELSE 0 END,
+			broken_since=CASE WHEN $6 THEN COALESCE(url_health.broken_since, now()) END,
+			checked_at=now()
+		returning consecutive_failures, broken_since, checked_at
+	`
+
+	err := hr.db.QueryRow(
+		query,
+		h.UrlId,
+		h.StatusCode,
+		h.Latency.Milliseconds(),
+		utils.NullString(h.FinalUrl),
+		utils.NullString(h.Error),
+		h.Broken,
+	).Scan(
+		&h.ConsecutiveFailures,
+		&h.BrokenSince,
+		&h.CheckedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (hr *healthRepo) GetBroken(params *repo.GetBrokenUrlsParams) (*repo.GetBrokenUrlsResult, error) {
+	result := repo.GetBrokenUrlsResult{
+		Urls: make([]*repo.BrokenUrl, 0),
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" limit %d offset %d ", params.Limit, offset)
+
+	filter := `
+		FROM urls
+		JOIN url_health h ON h.url_id = urls.id
+		WHERE user_id=$1 AND is_active AND deleted_at IS NULL AND h.consecutive_failures >= $2
+	`
+
+	query := `
+		SELECT ` + urlColumns + `,
+			h.status_code,
+			h.latency_ms,
+			h.final_url,
+			h.error,
+			h.consecutive_failures,
+			h.broken_since,
+			h.checked_at
+		` + filter + `
+		ORDER BY h.broken_since, urls.id
+		` + limit
+	rows, err := hr.db.Query(query, params.UserID, params.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			h         repo.UrlHealth
+			latencyMs int64
+			finalUrl  sql.NullString
+			errorText sql.NullString
+		)
+		u, err := scanUrl(rows,
+			&h.StatusCode,
+			&latencyMs,
+			&finalUrl,
+			&errorText,
+			&h.ConsecutiveFailures,
+			&h.BrokenSince,
+			&h.CheckedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		h.UrlId = u.Id
+		h.Latency = time.Duration(latencyMs) * time.Millisecond
+		h.FinalUrl = finalUrl.String
+		h.Error = errorText.String
+		h.Broken = true
+
+		result.Urls = append(result.Urls, &repo.BrokenUrl{
+			Url:    u,
+			Health: &h,
+		})
+	}
+
+	queryCount := `SELECT count(1) ` + filter
+	err = hr.db.QueryRow(queryCount, params.UserID, params.Threshold).Scan(&result.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
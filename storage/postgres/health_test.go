@@ -0,0 +1,73 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUrlHealth(t *testing.T) {
+	url := createUrl(t)
+
+	due, err := strg.Health().GetDue(time.Now(), 1000000)
+	require.NoError(t, err)
+	require.Contains(t, urlIds(due), url.Id)
+
+	for i := 1; i <= 2; i++ {
+		h, err := strg.Health().Save(&repo.UrlHealth{
+			UrlId:      url.Id,
+			StatusCode: 500,
+			Latency:    time.Second,
+			FinalUrl:   url.OriginalUrl,
+			Error:      "500 Internal Server Error",
+			Broken:     true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, i, h.ConsecutiveFailures)
+		require.NotNil(t, h.BrokenSince)
+	}
+
+	due, err = strg.Health().GetDue(time.Now().Add(-time.Hour), 1000000)
+	require.NoError(t, err)
+	require.NotContains(t, urlIds(due), url.Id)
+
+	result, err := strg.Health().GetBroken(&repo.GetBrokenUrlsParams{
+		Limit:     10,
+		Page:      1,
+		UserID:    url.UserId,
+		Threshold: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Urls, 1)
+	require.Equal(t, url.Id, result.Urls[0].Url.Id)
+	require.Equal(t, 500, result.Urls[0].Health.StatusCode)
+	require.Equal(t, time.Second, result.Urls[0].Health.Latency)
+
+	h, err := strg.Health().Save(&repo.UrlHealth{
+		UrlId:      url.Id,
+		StatusCode: 200,
+	})
+	require.NoError(t, err)
+	require.Zero(t, h.ConsecutiveFailures)
+	require.Nil(t, h.BrokenSince)
+
+	result, err = strg.Health().GetBroken(&repo.GetBrokenUrlsParams{
+		Limit:     10,
+		Page:      1,
+		UserID:    url.UserId,
+		Threshold: 1,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Urls)
+	deleteUser(t, url.UserId)
+}
+
+func urlIds(urls []*repo.Url) []int64 {
+	ids := make([]int64, 0, len(urls))
+	for _, u := range urls {
+		ids = append(ids, u.Id)
+	}
+	return ids
+}
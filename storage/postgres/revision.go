@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type revisionRepo struct {
+	db *sqlx.DB
+}
+
+func NewRevision(db *sqlx.DB) repo.RevisionStorageI {
+	return &revisionRepo{
+		db: db,
+	}
+}
+
+const revisionColumns = `
+	id,
+	url_id,
+	revision,
+	user_id,
+	original_url,
+	hashed_url,
+	max_clicks,
+	starts_at,
+	expires_at,
+	created_at
+`
+
+func scanRevision(row rowScanner) (*repo.UrlRevision, error) {
+	var (
+		result repo.UrlRevision
+		userId sql.NullInt64
+	)
+
+	err := row.Scan(
+		&result.Id,
+		&result.UrlId,
+		&result.Revision,
+		&userId,
+		&result.OriginalUrl,
+		&result.HashedUrl,
+		&result.MaxClicks,
+		&result.StartsAt,
+		&result.ExpiresAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result.UserId = userId.Int64
+
+	return &result, nil
+}
+
+func (rr *revisionRepo) GetAll(urlID int64) ([]*repo.UrlRevision, error) {
+	result := make([]*repo.UrlRevision, 0)
+
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM url_revisions
+		WHERE url_id=$1
+		ORDER BY revision desc
+	`
+	rows, err := rr.db.Query(query, urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+func (rr *revisionRepo) Get(urlID int64, revision int) (*repo.UrlRevision, error) {
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM url_revisions
+		WHERE url_id=$1 AND revision=$2
+	`
+
+	return scanRevision(rr.db.QueryRow(query, urlID, revision))
+}
+
+// insertRevision writes the tracked fields of the url as its next revision,
+// the caller has to hold the row lock of the url
+func insertRevision(tx *sqlx.Tx, url *repo.Url, userID int64, createdAt time.Time) error {
+	query := `
+		insert into url_revisions(
+			url_id,
+			revision,
+			user_id,
+			original_url,
+			hashed_url,
+			max_clicks,
+			starts_at,
+			expires_at,
+			created_at
+		) values (
+			$1,
+			(SELECT COALESCE(max(revision), 0) + 1 FROM url_revisions WHERE url_id=$1),
+			$2, $3, $4, $5, $6, $7, $8
+		)
+	`
+
+	_, err := tx.Exec(
+		query,
+		url.Id,
+		utils.NullInt64(userID),
+		url.OriginalUrl,
+		url.HashedUrl,
+		url.MaxClicks,
+		url.StartsAt,
+		url.ExpiresAt,
+		createdAt,
+	)
+	return err
+}
+
+// revisionChanged reports whether any of the fields kept in revisions differ
+func revisionChanged(a, b *repo.Url) bool {
+	return a.OriginalUrl != b.OriginalUrl ||
+		a.HashedUrl != b.HashedUrl ||
+		!equalInt64(a.MaxClicks, b.MaxClicks) ||
+		!equalTime(a.StartsAt, b.StartsAt) ||
+		!equalTime(a.ExpiresAt, b.ExpiresAt)
+}
+
+func equalInt64(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
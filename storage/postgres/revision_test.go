@@ -0,0 +1,42 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUrlRevisions(t *testing.T) {
+	url := createUrl(t)
+	originalUrl := url.OriginalUrl
+
+	// changes of untracked fields do not make revisions
+	url.Password = "hash"
+	_, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+
+	revisions, err := strg.Revision().GetAll(url.Id)
+	require.NoError(t, err)
+	require.Empty(t, revisions)
+
+	url.OriginalUrl = faker.URL()
+	_, err = strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+
+	revisions, err = strg.Revision().GetAll(url.Id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, 2, revisions[0].Revision)
+	require.Equal(t, url.OriginalUrl, revisions[0].OriginalUrl)
+	require.Equal(t, url.UserId, revisions[0].UserId)
+
+	revision, err := strg.Revision().Get(url.Id, 1)
+	require.NoError(t, err)
+	require.Equal(t, originalUrl, revision.OriginalUrl)
+
+	_, err = strg.Revision().Get(url.Id, 3)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, url.UserId)
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type ruleRepo struct {
+	db *sqlx.DB
+}
+
+func NewRule(db *sqlx.DB) repo.RuleStorageI {
+	return &ruleRepo{
+		db: db,
+	}
+}
+
+const ruleColumns = `
+	id,
+	url_id,
+	position,
+	os,
+	device,
+	language,
+	time_from,
+	time_to,
+	timezone,
+	target_url,
+	created_at
+`
+
+func scanRule(row rowScanner) (*repo.Rule, error) {
+	var (
+		result                   repo.Rule
+		os, device, lang, tzName sql.NullString
+	)
+
+	err := row.Scan(
+		&result.Id,
+		&result.UrlId,
+		&result.Position,
+		&os,
+		&device,
+		&lang,
+		&result.TimeFrom,
+		&result.TimeTo,
+		&tzName,
+		&result.TargetUrl,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result.OS = os.String
+	result.Device = device.String
+	result.Language = lang.String
+	result.Timezone = tzName.String
+
+	return &result, nil
+}
+
+func (rr *ruleRepo) Create(rule *repo.Rule) (*repo.Rule, error) {
+	query := `
+		insert into url_rules(
+			url_id,
+			position,
+			os,
+			device,
+			language,
+			time_from,
+			time_to,
+			timezone,
+			target_url
+		) values (
+			$1,
+			CASE WHEN $2 > 0 THEN $2 ELSE (
+				SELECT COALESCE(max(position), 0) + 1 FROM url_rules WHERE url_id=$1
+			) END,
+			$3, $4, $5, $6, $7, $8, $9
+		)
+		returning ` + ruleColumns
+
+	return scanRule(rr.db.QueryRow(
+		query,
+		rule.UrlId,
+		rule.Position,
+		utils.NullString(rule.OS),
+		utils.NullString(rule.Device),
+		utils.NullString(rule.Language),
+		rule.TimeFrom,
+		rule.TimeTo,
+		utils.NullString(rule.Timezone),
+		rule.TargetUrl,
+	))
+}
+
+func (rr *ruleRepo) Get(id int64) (*repo.Rule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM url_rules
+		WHERE id=$1
+	`
+
+	return scanRule(rr.db.QueryRow(query, id))
+}
+
+func (rr *ruleRepo) GetAll(urlID int64) ([]*repo.Rule, error) {
+	result := make([]*repo.Rule, 0)
+
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM url_rules
+		WHERE url_id=$1
+		ORDER BY position, id
+	`
+	rows, err := rr.db.Query(query, urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+func (rr *ruleRepo) Update(rule *repo.Rule) (*repo.Rule, error) {
+	query := `
+		update url_rules set
+			position=CASE WHEN $1 > 0 THEN $1 ELSE position END,
+			os=$2,
+			device=$3,
+			language=$4,
+			time_from=$5,
+			time_to=$6,
+			timezone=$7,
+			target_url=$8
+		where id=$9 and url_id=$10
+		returning ` + ruleColumns
+
+	return scanRule(rr.db.QueryRow(
+		query,
+		rule.Position,
+		utils.NullString(rule.OS),
+		utils.NullString(rule.Device),
+		utils.NullString(rule.Language),
+		rule.TimeFrom,
+		rule.TimeTo,
+		utils.NullString(rule.Timezone),
+		rule.TargetUrl,
+		rule.Id,
+		rule.UrlId,
+	))
+}
+
+func (rr *ruleRepo) Delete(id, urlID int64) error {
+	query := ` delete from url_rules where id=$1 and url_id=$2 `
+
+	res, err := rr.db.Exec(
+		query,
+		id,
+		urlID,
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
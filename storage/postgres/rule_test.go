@@ -0,0 +1,68 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createRule(t *testing.T, urlID int64) *repo.Rule {
+	from, to := 9*60, 18*60
+	rule, err := strg.Rule().Create(&repo.Rule{
+		UrlId:     urlID,
+		OS:        "iOS",
+		Device:    "mobile",
+		TimeFrom:  &from,
+		TimeTo:    &to,
+		Timezone:  "Asia/Tashkent",
+		TargetUrl: faker.URL(),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, rule.Id)
+	require.Equal(t, from, *rule.TimeFrom)
+	require.Empty(t, rule.Language)
+
+	return rule
+}
+
+func TestCreateRule(t *testing.T) {
+	url := createUrl(t)
+	rule1 := createRule(t, url.Id)
+	rule2 := createRule(t, url.Id)
+	require.Equal(t, rule1.Position+1, rule2.Position)
+	deleteUser(t, url.UserId)
+}
+
+func TestGetAllRules(t *testing.T) {
+	url := createUrl(t)
+	rule1 := createRule(t, url.Id)
+	rule2 := createRule(t, url.Id)
+
+	rule2.Position = rule1.Position - 1
+	_, err := strg.Rule().Update(rule2)
+	require.NoError(t, err)
+
+	rules, err := strg.Rule().GetAll(url.Id)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, rule2.Id, rules[0].Id)
+	deleteUser(t, url.UserId)
+}
+
+func TestDeleteRule(t *testing.T) {
+	url := createUrl(t)
+	rule := createRule(t, url.Id)
+
+	err := strg.Rule().Delete(rule.Id, url.Id+1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.Rule().Delete(rule.Id, url.Id)
+	require.NoError(t, err)
+
+	_, err = strg.Rule().Get(rule.Id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, url.UserId)
+}
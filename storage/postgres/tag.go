@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type tagRepo struct {
+	db *sqlx.DB
+}
+
+func NewTag(db *sqlx.DB) repo.TagStorageI {
+	return &tagRepo{
+		db: db,
+	}
+}
+
+func (tr *tagRepo) Create(tag *repo.Tag) (*repo.Tag, error) {
+	query := `
+		insert into tags(
+			user_id,
+			name
+		) values ($1, $2)
+		returning id, created_at
+	`
+
+	err := tr.db.QueryRow(
+		query,
+		tag.UserId,
+		tag.Name,
+	).Scan(
+		&tag.Id,
+		&tag.CreatedAt,
+	)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	return tag, nil
+}
+
+func (tr *tagRepo) Get(id int64) (*repo.Tag, error) {
+	var result repo.Tag
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			created_at
+		FROM tags
+		WHERE id=$1
+	`
+
+	err := tr.db.QueryRow(query, id).Scan(
+		&result.Id,
+		&result.UserId,
+		&result.Name,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (tr *tagRepo) GetAll(userID int64) ([]*repo.Tag, error) {
+	result := make([]*repo.Tag, 0)
+
+	query := `
+		SELECT
+			t.id,
+			t.user_id,
+			t.name,
+			t.created_at,
+			count(DISTINCT u.id),
+			(
+				SELECT count(1)
+				FROM url_clicks c
+				JOIN url_tags ut2 ON ut2.url_id = c.url_id
+				JOIN urls u2 ON u2.id = c.url_id
+				WHERE ut2.tag_id = t.id AND u2.deleted_at IS NULL
+			)
+		FROM tags t
+		LEFT JOIN url_tags ut ON ut.tag_id = t.id
+		LEFT JOIN urls u ON u.id = ut.url_id AND u.deleted_at IS NULL
+		WHERE t.user_id=$1
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+	rows, err := tr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t repo.Tag
+		err := rows.Scan(
+			&t.Id,
+			&t.UserId,
+			&t.Name,
+			&t.CreatedAt,
+			&t.Urls,
+			&t.Clicks,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &t)
+	}
+
+	return result, nil
+}
+
+func (tr *tagRepo) Update(tag *repo.Tag) (*repo.Tag, error) {
+	query := `
+		update tags set name=$1
+		where id=$2 and user_id=$3
+		returning created_at
+	`
+
+	err := tr.db.QueryRow(
+		query,
+		tag.Name,
+		tag.Id,
+		tag.UserId,
+	).Scan(
+		&tag.CreatedAt,
+	)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	return tag, nil
+}
+
+func (tr *tagRepo) Delete(id, userID int64) error {
+	query := ` delete from tags where id=$1 and user_id=$2 `
+
+	res, err := tr.db.Exec(
+		query,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (tr *tagRepo) SetUrlTags(urlID, userID int64, tagIDs []int64) error {
+	tx, err := tr.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = setUrlTags(tx, urlID, userID, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setUrlTags replaces tags of the url within tx and returns the ids of
+// the tags it has now, sorted
+func setUrlTags(tx *sqlx.Tx, urlID, userID int64, tagIDs []int64) ([]int64, error) {
+	_, err := tx.Exec(` delete from url_tags where url_id=$1 `, urlID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result pq.Int64Array
+	err = tx.QueryRow(`
+		with inserted as (
+			insert into url_tags(url_id, tag_id)
+			select $1, id from tags where user_id=$2 and id = ANY($3)
+			returning tag_id
+		)
+		select ARRAY(select tag_id from inserted order by tag_id)
+	`, urlID, userID, pq.Array(tagIDs)).Scan(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]int64{}, result...), nil
+}
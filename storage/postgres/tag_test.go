@@ -0,0 +1,113 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createTag(t *testing.T, userID int64) *repo.Tag {
+	tag, err := strg.Tag().Create(&repo.Tag{
+		UserId: userID,
+		Name:   faker.Word() + faker.UUIDDigit(),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, tag.Id)
+	require.NotZero(t, tag.CreatedAt)
+
+	return tag
+}
+
+func TestCreateTag(t *testing.T) {
+	user := createUser(t)
+	tag := createTag(t, user.Id)
+
+	_, err := strg.Tag().Create(&repo.Tag{
+		UserId: user.Id,
+		Name:   tag.Name,
+	})
+	require.ErrorIs(t, err, repo.ErrAlreadyExists)
+	deleteUser(t, user.Id)
+}
+
+func TestUpdateTag(t *testing.T) {
+	user := createUser(t)
+	tag := createTag(t, user.Id)
+
+	_, err := strg.Tag().Update(&repo.Tag{
+		Id:     tag.Id,
+		UserId: -1,
+		Name:   "renamed",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = strg.Tag().Update(&repo.Tag{
+		Id:     tag.Id,
+		UserId: user.Id,
+		Name:   "renamed",
+	})
+	require.NoError(t, err)
+
+	tag2, err := strg.Tag().Get(tag.Id)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", tag2.Name)
+	deleteUser(t, user.Id)
+}
+
+func TestSetUrlTags(t *testing.T) {
+	url := createUrl(t)
+	tag := createTag(t, url.UserId)
+	tag2 := createTag(t, url.UserId)
+	createClick(t, url.Id)
+	createClick(t, url.Id)
+
+	other := createUser(t)
+	foreign := createTag(t, other.Id)
+
+	err := strg.Tag().SetUrlTags(url.Id, url.UserId, []int64{tag.Id, tag2.Id, foreign.Id})
+	require.NoError(t, err)
+
+	url2, err := strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int64{tag.Id, tag2.Id}, url2.TagIds)
+
+	result, err := strg.Url().GetAll(&repo.GetAllUrlsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: url.UserId,
+		TagID:  tag.Id,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Urls, 1)
+
+	tags, err := strg.Tag().GetAll(url.UserId)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	for _, tg := range tags {
+		require.Equal(t, int64(1), tg.Urls)
+		require.Equal(t, int64(2), tg.Clicks)
+	}
+
+	err = strg.Tag().SetUrlTags(url.Id, url.UserId, []int64{})
+	require.NoError(t, err)
+	url2, err = strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.Empty(t, url2.TagIds)
+
+	deleteUser(t, other.Id)
+	deleteUser(t, url.UserId)
+}
+
+func TestDeleteTag(t *testing.T) {
+	user := createUser(t)
+	tag := createTag(t, user.Id)
+
+	err := strg.Tag().Delete(tag.Id, -1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = strg.Tag().Delete(tag.Id, user.Id)
+	require.NoError(t, err)
+	deleteUser(t, user.Id)
+}
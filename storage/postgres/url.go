@@ -2,10 +2,14 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/SaidovZohid/competition-project/pkg/utils"
 	"github.com/SaidovZohid/competition-project/storage/repo"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type urlRepo struct {
@@ -18,70 +22,262 @@ func NewUrl(db *sqlx.DB) repo.UrlStorageI {
 	}
 }
 
+const createUrlQuery = `
+	insert into urls(
+		user_id,
+		original_url,
+		hashed_url,
+		max_clicks,
+		expires_at,
+		domain_id,
+		password,
+		starts_at,
+		folder_id,
+		fallback_url,
+		preview_title,
+		preview_description,
+		preview_image
+	) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	returning id, is_active, created_at
+`
+
+// urlColumns is the select list scanUrl expects
+const urlColumns = `
+	id,
+	user_id,
+	original_url,
+	hashed_url,
+	max_clicks,
+	starts_at,
+	expires_at,
+	domain_id,
+	password,
+	is_active,
+	inactive_reason,
+	deactivated_at,
+	sticky_destinations,
+	folder_id,
+	fallback_url,
+	preview_title,
+	preview_description,
+	preview_image,
+	meta_title,
+	meta_description,
+	canonical_url,
+	favicon_url,
+	meta_fetched_at,
+	deleted_at,
+	created_at,
+	ARRAY(SELECT ut.tag_id FROM url_tags ut WHERE ut.url_id = urls.id ORDER BY ut.tag_id) AS tag_ids
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUrl scans a row selected with urlColumns, extra destinations are
+// scanned after the url columns
+func scanUrl(row rowScanner, extra ...interface{}) (*repo.Url, error) {
+	var (
+		result   repo.Url
+		domainId sql.NullInt64
+		folderId sql.NullInt64
+		tagIds   pq.Int64Array
+		password sql.NullString
+		reason   sql.NullString
+		fallback sql.NullString
+		preview  [3]sql.NullString
+		meta     [4]sql.NullString
+	)
+
+	dest := []interface{}{
+		&result.Id,
+		&result.UserId,
+		&result.OriginalUrl,
+		&result.HashedUrl,
+		&result.MaxClicks,
+		&result.StartsAt,
+		&result.ExpiresAt,
+		&domainId,
+		&password,
+		&result.Active,
+		&reason,
+		&result.DeactivatedAt,
+		&result.StickyDestinations,
+		&folderId,
+		&fallback,
+		&preview[0],
+		&preview[1],
+		&preview[2],
+		&meta[0],
+		&meta[1],
+		&meta[2],
+		&meta[3],
+		&result.MetaFetchedAt,
+		&result.DeletedAt,
+		&result.CreatedAt,
+		&tagIds,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	result.DomainId = domainId.Int64
+	result.FolderId = folderId.Int64
+	result.TagIds = append([]int64{}, tagIds...)
+	result.Password = password.String
+	result.InactiveReason = reason.String
+	result.FallbackUrl = fallback.String
+	result.PreviewTitle = preview[0].String
+	result.PreviewDescription = preview[1].String
+	result.PreviewImage = preview[2].String
+	result.MetaTitle = meta[0].String
+	result.MetaDescription = meta[1].String
+	result.CanonicalUrl = meta[2].String
+	result.FaviconUrl = meta[3].String
+
+	return &result, nil
+}
+
 func (ur *urlRepo) Create(url *repo.Url) (*repo.Url, error) {
-	query := `
-		insert into urls(
-			user_id,
-			original_url,
-			hashed_url,
-			max_clicks,
-			expires_at
-		) values ($1, $2, $3, $4, $5)
-		returning id, created_at
-	`
-	if *url.MaxClicks == 0 {
+	tx, err := ur.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = createUrl(tx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// createUrl inserts the url and attaches its tags within tx
+func createUrl(tx *sqlx.Tx, url *repo.Url) error {
+	if url.MaxClicks != nil && *url.MaxClicks == 0 {
 		url.MaxClicks = nil
 	}
-	row := ur.db.QueryRow(
-		query,
+	row := tx.QueryRow(
+		createUrlQuery,
 		url.UserId,
 		url.OriginalUrl,
 		url.HashedUrl,
 		url.MaxClicks,
 		url.ExpiresAt,
+		utils.NullInt64(url.DomainId),
+		utils.NullString(url.Password),
+		url.StartsAt,
+		utils.NullInt64(url.FolderId),
+		utils.NullString(url.FallbackUrl),
+		utils.NullString(url.PreviewTitle),
+		utils.NullString(url.PreviewDescription),
+		utils.NullString(url.PreviewImage),
 	)
 
 	err := row.Scan(
 		&url.Id,
+		&url.Active,
 		&url.CreatedAt,
 	)
 	if err != nil {
-		return nil, err
+		return parseError(err)
 	}
 
-	return url, nil
-}
+	tagIDs, err := setUrlTags(tx, url.Id, url.UserId, url.TagIds)
+	if err != nil {
+		return err
+	}
+	url.TagIds = tagIDs
 
-func (ur *urlRepo) Get(url string) (*repo.Url, error) {
-	var result repo.Url
+	return nil
+}
 
-	query := fmt.Sprintf(`
-		SELECT
-			id,
-			user_id,
-			original_url,
-			hashed_url,
-			max_clicks,
-			expires_at,
-			created_at
-		FROM urls
-		WHERE hashed_url='%s'
-	`, url)
+func (ur *urlRepo) CreateBulk(urls []*repo.Url, newSlug repo.SlugFunc) ([]error, error) {
+	rowErrors := make([]error, len(urls))
 
-	err := ur.db.QueryRow(query).Scan(
-		&result.Id,
-		&result.UserId,
-		&result.OriginalUrl,
-		&result.HashedUrl,
-		&result.MaxClicks,
-		&result.ExpiresAt,
-		&result.CreatedAt,
-	)
+	tx, err := ur.db.Beginx()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return &result, nil
+	// every attempt gets its own savepoint, so a failed row is rolled back
+	// alone and does not abort the whole transaction
+	for i, url := range urls {
+		generated := url.HashedUrl == ""
+
+		for attempt := 0; ; attempt++ {
+			if generated {
+				slug, err := newSlug(url, attempt)
+				if err != nil {
+					url.HashedUrl = ""
+					rowErrors[i] = err
+					break
+				}
+				url.HashedUrl = slug
+			}
+
+			if _, err := tx.Exec(" SAVEPOINT bulk_row "); err != nil {
+				return nil, err
+			}
+
+			rowErr := createUrl(tx, url)
+			if rowErr == nil {
+				if _, err := tx.Exec(" RELEASE SAVEPOINT bulk_row "); err != nil {
+					return nil, err
+				}
+				break
+			}
+
+			url.Id = 0
+			if _, err := tx.Exec(" ROLLBACK TO SAVEPOINT bulk_row "); err != nil {
+				return nil, err
+			}
+			if !generated || !errors.Is(rowErr, repo.ErrAlreadyExists) {
+				rowErrors[i] = rowErr
+				break
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return rowErrors, nil
+}
+
+func (ur *urlRepo) Get(slug string, domainID int64) (*repo.Url, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE hashed_url=$1 AND domain_id IS NOT DISTINCT FROM $2 AND deleted_at IS NULL
+	`
+
+	return scanUrl(ur.db.QueryRow(query, slug, utils.NullInt64(domainID)))
+}
+
+func (ur *urlRepo) GetByID(id int64) (*repo.Url, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE id=$1 AND deleted_at IS NULL
+	`
+
+	return scanUrl(ur.db.QueryRow(query, id))
+}
+
+var urlSortColumns = map[string]string{
+	"":           "created_at",
+	"created_at": "created_at",
+	"expires_at": "expires_at",
+	"clicks":     "clicks",
 }
 
 func (ur *urlRepo) GetAll(params *repo.GetAllUrlsParams) (*repo.GetAllUrlsResult, error) {
@@ -93,32 +289,63 @@ func (ur *urlRepo) GetAll(params *repo.GetAllUrlsParams) (*repo.GetAllUrlsResult
 
 	limit := fmt.Sprintf(" limit %d offset %d ", params.Limit, offset)
 
-	filter := " where true "
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	filter := " where deleted_at IS NULL "
+	if params.Deleted {
+		filter = " where deleted_at IS NOT NULL "
+	}
 	if params.Search != "" {
-		str := "%" + params.Search + "%"
-		filter += fmt.Sprintf(`
-		AND original_url ilike '%s' or hashed_url ilike '%s'	`,
-			str, str,
-		)
+		str := arg("%" + params.Search + "%")
+		filter += " AND (original_url ilike " + str + " OR hashed_url ilike " + str + ") "
 	}
 	if params.UserID != 0 {
-		filter += fmt.Sprintf(" AND user_id = %d ", params.UserID)
+		filter += " AND user_id = " + arg(params.UserID)
+	}
+	switch params.Status {
+	case repo.UrlStatusActive:
+		filter += " AND is_active AND (starts_at IS NULL OR starts_at <= now()) AND (expires_at IS NULL OR expires_at > now()) AND (max_clicks IS NULL OR max_clicks > 0) "
+	case repo.UrlStatusScheduled:
+		filter += " AND is_active AND starts_at > now() "
+	case repo.UrlStatusExpired:
+		filter += " AND expires_at <= now() "
+	case repo.UrlStatusExhausted:
+		filter += " AND max_clicks <= 0 "
+	}
+	if params.TagID != 0 {
+		filter += " AND EXISTS (SELECT 1 FROM url_tags t WHERE t.url_id = urls.id AND t.tag_id = " + arg(params.TagID) + ") "
+	}
+	if params.FolderID != 0 {
+		filter += " AND folder_id = " + arg(params.FolderID)
+	}
+	if params.From != nil {
+		filter += " AND created_at >= " + arg(*params.From)
+	}
+	if params.To != nil {
+		filter += " AND created_at < " + arg(*params.To)
+	}
+
+	sortColumn, ok := urlSortColumns[params.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort column: %s", params.SortBy)
+	}
+	order := " desc nulls last "
+	if params.Ascending {
+		order = " asc nulls last "
 	}
 
 	query := `
-		SELECT
-			id,
-			user_id,
-			original_url,
-			hashed_url,
-			max_clicks,
-			expires_at,
-			created_at
+		SELECT ` + urlColumns + `,
+			(SELECT count(1) FROM url_clicks c WHERE c.url_id = urls.id) AS clicks
 		FROM urls
 		` + filter + `
-		ORDER BY created_at desc
+		ORDER BY ` + sortColumn + order + `, id desc
 		` + limit
-	rows, err := ur.db.Query(query)
+	rows, err := ur.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -126,27 +353,18 @@ func (ur *urlRepo) GetAll(params *repo.GetAllUrlsParams) (*repo.GetAllUrlsResult
 	defer rows.Close()
 
 	for rows.Next() {
-		var (
-			u repo.Url
-		)
-		err := rows.Scan(
-			&u.Id,
-			&u.UserId,
-			&u.OriginalUrl,
-			&u.HashedUrl,
-			&u.MaxClicks,
-			&u.ExpiresAt,
-			&u.CreatedAt,
-		)
+		var clicks int64
+		u, err := scanUrl(rows, &clicks)
 		if err != nil {
 			return nil, err
 		}
+		u.Clicks = clicks
 
-		result.Urls = append(result.Urls, &u)
+		result.Urls = append(result.Urls, u)
 	}
 
 	queryCount := `SELECT count(1) FROM urls ` + filter
-	err = ur.db.QueryRow(queryCount).Scan(&result.Count)
+	err = ur.db.QueryRow(queryCount, args...).Scan(&result.Count)
 	if err != nil {
 		return nil, err
 	}
@@ -154,49 +372,158 @@ func (ur *urlRepo) GetAll(params *repo.GetAllUrlsParams) (*repo.GetAllUrlsResult
 	return &result, nil
 }
 
-func (ur *urlRepo) Update(url *repo.Url) (*repo.Url, error) {
-	var result repo.Url
+func (ur *urlRepo) Export(userID int64, fn func(u *repo.Url) error) error {
+	query := `
+		SELECT ` + urlColumns + `,
+			(SELECT count(1) FROM url_clicks c WHERE c.url_id = urls.id) AS clicks
+		FROM urls
+		WHERE user_id=$1 AND deleted_at IS NULL
+		ORDER BY id
+	`
+	rows, err := ur.db.Query(query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var clicks int64
+		u, err := scanUrl(rows, &clicks)
+		if err != nil {
+			return err
+		}
+		u.Clicks = clicks
+
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (ur *urlRepo) Update(url *repo.Url, actorID int64) (*repo.Url, error) {
+	tx, err := ur.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	old, err := scanUrl(tx.QueryRow(`
+		SELECT `+urlColumns+`
+		FROM urls
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+		FOR UPDATE
+	`, url.Id, url.UserId))
+	if err != nil {
+		return nil, err
+	}
+
+	// a new expiry or click limit may bring an inactive url back
+	reason := endedReason(url, time.Now())
 
 	query := `
 		update urls set
-			hashed_url=$1,
-			max_clicks=$2,
-			expires_at=$3
-		where id=$4 and user_id=$5
-		returning 
-			id, 
-			user_id, 
-			original_url,
-			hashed_url,
-			max_clicks,
-			expires_at,
-			created_at
-	`
-	err := ur.db.QueryRow(
+			original_url=COALESCE(NULLIF($1, ''), original_url),
+			hashed_url=$2,
+			max_clicks=$3,
+			expires_at=$4,
+			password=$5,
+			starts_at=$6,
+			is_active=$7,
+			inactive_reason=$8,
+			deactivated_at=CASE WHEN $7 THEN NULL ELSE COALESCE(deactivated_at, now()) END,
+			folder_id=$11,
+			fallback_url=$12,
+			preview_title=$13,
+			preview_description=$14,
+			preview_image=$15
+		where id=$9 and user_id=$10
+		returning ` + urlColumns
+
+	result, err := scanUrl(tx.QueryRow(
 		query,
+		url.OriginalUrl,
 		url.HashedUrl,
 		url.MaxClicks,
 		url.ExpiresAt,
+		utils.NullString(url.Password),
+		url.StartsAt,
+		reason == "",
+		utils.NullString(reason),
 		url.Id,
 		url.UserId,
-	).Scan(
-		&result.Id,
-		&result.UserId,
-		&result.OriginalUrl,
-		&result.HashedUrl,
-		&result.MaxClicks,
-		&result.ExpiresAt,
-		&result.CreatedAt,
-	)
+		utils.NullInt64(url.FolderId),
+		utils.NullString(url.FallbackUrl),
+		utils.NullString(url.PreviewTitle),
+		utils.NullString(url.PreviewDescription),
+		utils.NullString(url.PreviewImage),
+	))
 	if err != nil {
+		return nil, parseError(err)
+	}
+
+	if url.TagIds != nil {
+		result.TagIds, err = setUrlTags(tx, url.Id, url.UserId, url.TagIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// checks and metadata of the old destination say nothing about the new one
+	if old.OriginalUrl != result.OriginalUrl {
+		_, err = tx.Exec(` delete from url_health where url_id=$1 `, url.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(`
+			update urls set
+				meta_title=NULL,
+				meta_description=NULL,
+				canonical_url=NULL,
+				favicon_url=NULL,
+				meta_fetched_at=NULL
+			where id=$1
+		`, url.Id)
+		if err != nil {
+			return nil, err
+		}
+		result.MetaTitle = ""
+		result.MetaDescription = ""
+		result.CanonicalUrl = ""
+		result.FaviconUrl = ""
+		result.MetaFetchedAt = nil
+	}
+
+	if revisionChanged(old, result) {
+		var count int
+		err = tx.QueryRow(` SELECT count(1) FROM url_revisions WHERE url_id=$1 `, url.Id).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			err = insertRevision(tx, old, old.UserId, old.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = insertRevision(tx, result, actorID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 func (ur *urlRepo) Delete(id, userID int64) error {
-	query := ` delete from urls where id=$1 and user_id=$2 `
+	query := ` update urls set deleted_at=now() where id=$1 and user_id=$2 and deleted_at IS NULL `
 
 	res, err := ur.db.Exec(
 		query,
@@ -213,16 +540,143 @@ func (ur *urlRepo) Delete(id, userID int64) error {
 	return nil
 }
 
-func (ur *urlRepo) DecrementClick(url string) error {
-	query := fmt.Sprintf(" UPDATE urls SET max_clicks = max_clicks - 1 WHERE hashed_url LIKE '%s'", "%"+url+"%")
+func (ur *urlRepo) Restore(id, userID int64, since time.Time) (*repo.Url, error) {
+	query := `
+		update urls set deleted_at=NULL
+		where id=$1 and user_id=$2 and deleted_at >= $3
+		returning ` + urlColumns
 
-	res, err := ur.db.Exec(query)
+	return scanUrl(ur.db.QueryRow(query, id, userID, since))
+}
+
+func (ur *urlRepo) Purge(before time.Time) (int64, error) {
+	res, err := ur.db.Exec(` delete from urls where deleted_at < $1 `, before)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	if count, _ := res.RowsAffected(); count == 0 {
-		return sql.ErrNoRows
+
+	return res.RowsAffected()
+}
+
+func (ur *urlRepo) GetMissingMetadata(limit int) ([]*repo.Url, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE meta_fetched_at IS NULL AND deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+	rows, err := ur.db.Query(query, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	result := make([]*repo.Url, 0)
+	for rows.Next() {
+		u, err := scanUrl(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	return result, rows.Err()
+}
+
+func (ur *urlRepo) SetMetadata(url *repo.Url) error {
+	query := `
+		update urls set
+			meta_title=$1,
+			meta_description=$2,
+			canonical_url=$3,
+			favicon_url=$4,
+			meta_fetched_at=now()
+		where id=$5 and original_url=$6
+		returning meta_fetched_at
+	`
+
+	err := ur.db.QueryRow(
+		query,
+		utils.NullString(url.MetaTitle),
+		utils.NullString(url.MetaDescription),
+		utils.NullString(url.CanonicalUrl),
+		utils.NullString(url.FaviconUrl),
+		url.Id,
+		url.OriginalUrl,
+	).Scan(&url.MetaFetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	return err
+}
+
+func (ur *urlRepo) ConsumeClick(id int64) (int64, error) {
+	var remaining int64
+
+	// the check and the decrement are one statement, so concurrent
+	// redirects can never take more clicks than max_clicks allows
+	query := `
+		UPDATE urls SET max_clicks = max_clicks - 1
+		WHERE id=$1 AND max_clicks > 0
+		RETURNING max_clicks
+	`
+
+	err := ur.db.QueryRow(query, id).Scan(&remaining)
+	if err != nil {
+		return 0, err
+	}
+
+	return remaining, nil
+}
+
+func (ur *urlRepo) DeactivateEnded() ([]*repo.Url, error) {
+	query := `
+		UPDATE urls SET
+			is_active=false,
+			inactive_reason=CASE WHEN expires_at <= now() THEN $1 ELSE $2 END,
+			deactivated_at=now()
+		WHERE is_active AND deleted_at IS NULL AND (expires_at <= now() OR max_clicks <= 0)
+		RETURNING ` + urlColumns
+
+	rows, err := ur.db.Query(query, repo.UrlStatusExpired, repo.UrlStatusExhausted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*repo.Url, 0)
+	for rows.Next() {
+		u, err := scanUrl(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	return result, rows.Err()
+}
+
+// endedReason returns why the url can not be active anymore, or an empty
+// string if it still can
+func endedReason(url *repo.Url, now time.Time) string {
+	if url.ExpiresAt != nil && !url.ExpiresAt.After(now) {
+		return repo.UrlStatusExpired
+	}
+	if url.MaxClicks != nil && *url.MaxClicks <= 0 {
+		return repo.UrlStatusExhausted
+	}
+	return ""
+}
+
+func (ur *urlRepo) NextSlugSequence() (int64, error) {
+	var n int64
+
+	err := ur.db.QueryRow(` SELECT nextval('urls_slug_seq') `).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
 }
@@ -2,10 +2,14 @@ package postgres_test
 
 import (
 	"database/sql"
+	"errors"
 	"log"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/SaidovZohid/competition-project/pkg/utils"
 	"github.com/SaidovZohid/competition-project/storage/repo"
 	"github.com/bxcodec/faker/v4"
 	"github.com/stretchr/testify/require"
@@ -18,7 +22,7 @@ func createUrl(t *testing.T) *repo.Url {
 	url1 := repo.Url{
 		UserId:      user.Id,
 		OriginalUrl: faker.URL(),
-		HashedUrl:   faker.URL(),
+		HashedUrl:   utils.RandomString(10),
 		MaxClicks:   &click,
 		ExpiresAt:   &tm,
 	}
@@ -43,9 +47,58 @@ func TestCreateUrl(t *testing.T) {
 	deleteUser(t, url.UserId)
 }
 
+func TestCreateBulkUrls(t *testing.T) {
+	user := createUser(t)
+	slug := utils.RandomString(10)
+	urls := []*repo.Url{
+		{UserId: user.Id, OriginalUrl: faker.URL(), HashedUrl: slug},
+		{UserId: user.Id, OriginalUrl: faker.URL(), HashedUrl: utils.RandomString(10)},
+		{UserId: user.Id, OriginalUrl: faker.URL(), HashedUrl: slug},
+	}
+
+	// the last row collides with the first before it gets a free slug
+	free := utils.RandomString(10)
+	urls = append(urls, &repo.Url{UserId: user.Id, OriginalUrl: faker.URL()})
+	newSlug := func(u *repo.Url, attempt int) (string, error) {
+		if attempt == 0 {
+			return slug, nil
+		}
+		return free, nil
+	}
+
+	rowErrors, err := strg.Url().CreateBulk(urls, newSlug)
+	require.NoError(t, err)
+	require.Len(t, rowErrors, 4)
+	require.NoError(t, rowErrors[0])
+	require.NoError(t, rowErrors[1])
+	require.ErrorIs(t, rowErrors[2], repo.ErrAlreadyExists)
+	require.NoError(t, rowErrors[3])
+	require.NotZero(t, urls[0].Id)
+	require.NotZero(t, urls[1].Id)
+	require.Zero(t, urls[2].Id)
+	require.NotZero(t, urls[3].Id)
+	require.Equal(t, free, urls[3].HashedUrl)
+
+	// generated slugs are given up when newSlug fails
+	errNoSlug := errors.New("no slug")
+	rowErrors, err = strg.Url().CreateBulk([]*repo.Url{{UserId: user.Id, OriginalUrl: faker.URL()}}, func(u *repo.Url, attempt int) (string, error) {
+		if attempt == 0 {
+			return slug, nil
+		}
+		return "", errNoSlug
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, rowErrors[0], errNoSlug)
+
+	url, err := strg.Url().Get(slug, 0)
+	require.NoError(t, err)
+	require.Equal(t, urls[0].Id, url.Id)
+	deleteUser(t, user.Id)
+}
+
 func TestGetUrl(t *testing.T) {
 	url := createUrl(t)
-	url2, err := strg.Url().Get(url.HashedUrl)
+	url2, err := strg.Url().Get(url.HashedUrl, 0)
 	require.NoError(t, err)
 	require.Equal(t, url.Id, url2.Id)
 	require.Equal(t, url.UserId, url2.UserId)
@@ -67,25 +120,392 @@ func TestDeleteUrl(t *testing.T) {
 	deleteUser(t, url.UserId)
 }
 
+func TestRestoreUrl(t *testing.T) {
+	url := createUrl(t)
+	deleteUrl(t, url.Id, url.UserId)
+
+	_, err := strg.Url().GetByID(url.Id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	trash, err := strg.Url().GetAll(&repo.GetAllUrlsParams{
+		Limit:   10,
+		Page:    1,
+		UserID:  url.UserId,
+		Deleted: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, trash.Urls, 1)
+	require.NotNil(t, trash.Urls[0].DeletedAt)
+
+	// deleted before the retention window
+	_, err = strg.Url().Restore(url.Id, url.UserId, time.Now().Add(time.Hour))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	url2, err := strg.Url().Restore(url.Id, url.UserId, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, url2.DeletedAt)
+
+	deleteUrl(t, url.Id, url.UserId)
+	_, err = strg.Url().Purge(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	_, err = strg.Url().Restore(url.Id, url.UserId, time.Now().Add(-time.Hour))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, url.UserId)
+}
+
 func TestUpdateUrl(t *testing.T) {
 	url := createUrl(t)
 	click := int64(100)
 	url2, err := strg.Url().Update(&repo.Url{
 		Id:        url.Id,
 		UserId:    url.UserId,
-		HashedUrl: faker.URL(),
+		HashedUrl: utils.RandomString(10),
 		MaxClicks: &click,
-	})
+	}, url.UserId)
 	require.NoError(t, err)
 	require.NotEmpty(t, url2)
 	deleteUser(t, url.UserId)
 }
 
-func TestDecrementMaxClick(t *testing.T) {
+func TestUrlTags(t *testing.T) {
+	user := createUser(t)
+	tag := createTag(t, user.Id)
+	tag2 := createTag(t, user.Id)
+	other := createUser(t)
+	foreign := createTag(t, other.Id)
+
+	url, err := strg.Url().Create(&repo.Url{
+		UserId:      user.Id,
+		OriginalUrl: faker.URL(),
+		HashedUrl:   utils.RandomString(10),
+		TagIds:      []int64{tag2.Id, foreign.Id, tag.Id},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{tag.Id, tag2.Id}, url.TagIds)
+
+	// tags are kept when TagIds is nil
+	url2, err := strg.Url().Update(&repo.Url{
+		Id:          url.Id,
+		UserId:      user.Id,
+		OriginalUrl: url.OriginalUrl,
+		HashedUrl:   url.HashedUrl,
+	}, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, []int64{tag.Id, tag2.Id}, url2.TagIds)
+
+	url2, err = strg.Url().Update(&repo.Url{
+		Id:          url.Id,
+		UserId:      user.Id,
+		OriginalUrl: url.OriginalUrl,
+		HashedUrl:   url.HashedUrl,
+		TagIds:      []int64{tag2.Id},
+	}, user.Id)
+	require.NoError(t, err)
+	require.Equal(t, []int64{tag2.Id}, url2.TagIds)
+
+	// a failed update leaves the tags alone
+	taken := createUrl(t)
+	_, err = strg.Url().Update(&repo.Url{
+		Id:          url.Id,
+		UserId:      user.Id,
+		OriginalUrl: url.OriginalUrl,
+		HashedUrl:   taken.HashedUrl,
+		TagIds:      []int64{},
+	}, user.Id)
+	require.ErrorIs(t, err, repo.ErrAlreadyExists)
+	url2, err = strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.Equal(t, []int64{tag2.Id}, url2.TagIds)
+
+	deleteUser(t, taken.UserId)
+	deleteUser(t, other.Id)
+	deleteUser(t, user.Id)
+}
+
+func TestUrlPassword(t *testing.T) {
+	url := createUrl(t)
+	hash, err := utils.HashPassword("secret")
+	require.NoError(t, err)
+
+	url.Password = hash
+	url2, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.Equal(t, hash, url2.Password)
+
+	url3, err := strg.Url().Get(url.HashedUrl, 0)
+	require.NoError(t, err)
+	require.NoError(t, utils.CheckPassword("secret", url3.Password))
+
+	url.Password = ""
+	url4, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.Empty(t, url4.Password)
+	deleteUser(t, url.UserId)
+}
+
+func TestUrlFallbackUrl(t *testing.T) {
+	url := createUrl(t)
+	require.Empty(t, url.FallbackUrl)
+
+	url.FallbackUrl = faker.URL()
+	url2, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.Equal(t, url.FallbackUrl, url2.FallbackUrl)
+
+	url.FallbackUrl = ""
+	url3, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.Empty(t, url3.FallbackUrl)
+	deleteUser(t, url.UserId)
+}
+
+func TestUrlPreview(t *testing.T) {
 	url := createUrl(t)
-	log.Println(url.MaxClicks)
-	err := strg.Url().DecrementClick(url.HashedUrl)
+	url.PreviewTitle = faker.Sentence()
+	url.PreviewDescription = faker.Paragraph()
+	url.PreviewImage = faker.URL()
+	_, err := strg.Url().Update(url, url.UserId)
 	require.NoError(t, err)
+
+	url2, err := strg.Url().Get(url.HashedUrl, 0)
+	require.NoError(t, err)
+	require.Equal(t, url.PreviewTitle, url2.PreviewTitle)
+	require.Equal(t, url.PreviewDescription, url2.PreviewDescription)
+	require.Equal(t, url.PreviewImage, url2.PreviewImage)
+	deleteUser(t, url.UserId)
+}
+
+func TestUrlMetadata(t *testing.T) {
+	url := createUrl(t)
+	require.Nil(t, url.MetaFetchedAt)
+
+	urls, err := strg.Url().GetMissingMetadata(10)
+	require.NoError(t, err)
+	require.Contains(t, urlIds(urls), url.Id)
+
+	// metadata of an old destination is dropped
+	err = strg.Url().SetMetadata(&repo.Url{
+		Id:          url.Id,
+		OriginalUrl: faker.URL(),
+		MetaTitle:   faker.Sentence(),
+	})
+	require.NoError(t, err)
+	url2, err := strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.Nil(t, url2.MetaFetchedAt)
+
+	url.MetaTitle = faker.Sentence()
+	url.MetaDescription = faker.Paragraph()
+	url.CanonicalUrl = faker.URL()
+	url.FaviconUrl = faker.URL()
+	err = strg.Url().SetMetadata(url)
+	require.NoError(t, err)
+	require.NotNil(t, url.MetaFetchedAt)
+
+	url2, err = strg.Url().Get(url.HashedUrl, 0)
+	require.NoError(t, err)
+	require.NotNil(t, url2.MetaFetchedAt)
+	require.Equal(t, url.MetaTitle, url2.MetaTitle)
+	require.Equal(t, url.MetaDescription, url2.MetaDescription)
+	require.Equal(t, url.CanonicalUrl, url2.CanonicalUrl)
+	require.Equal(t, url.FaviconUrl, url2.FaviconUrl)
+
+	urls, err = strg.Url().GetMissingMetadata(10)
+	require.NoError(t, err)
+	require.NotContains(t, urlIds(urls), url.Id)
+
+	// a new destination has to be fetched again
+	url.OriginalUrl = faker.URL()
+	url3, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.Nil(t, url3.MetaFetchedAt)
+	require.Empty(t, url3.MetaTitle)
+	deleteUser(t, url.UserId)
+}
+
+func TestDeactivateEndedUrls(t *testing.T) {
+	url := createUrl(t)
+	require.True(t, url.Active)
+
+	click := int64(1)
+	url.MaxClicks = &click
+	_, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	_, err = strg.Url().ConsumeClick(url.Id)
+	require.NoError(t, err)
+
+	urls, err := strg.Url().DeactivateEnded()
+	require.NoError(t, err)
+	var found bool
+	for _, u := range urls {
+		if u.Id == url.Id {
+			found = true
+			require.False(t, u.Active)
+			require.Equal(t, repo.UrlStatusExhausted, u.InactiveReason)
+			require.NotNil(t, u.DeactivatedAt)
+		}
+	}
+	require.True(t, found)
+
+	expired := time.Now().Add(-time.Minute)
+	url.MaxClicks = nil
+	url.ExpiresAt = &expired
+	url2, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.False(t, url2.Active)
+	require.Equal(t, repo.UrlStatusExpired, url2.InactiveReason)
+
+	url.ExpiresAt = nil
+	url3, err := strg.Url().Update(url, url.UserId)
+	require.NoError(t, err)
+	require.True(t, url3.Active)
+	require.Empty(t, url3.InactiveReason)
+	deleteUser(t, url.UserId)
+}
+
+func TestDeactivateEndedSkipsTrash(t *testing.T) {
+	user := createUser(t)
+	expired := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	create := func(expiresAt *time.Time) *repo.Url {
+		url, err := strg.Url().Create(&repo.Url{
+			UserId:      user.Id,
+			OriginalUrl: faker.URL(),
+			HashedUrl:   utils.RandomString(10),
+			ExpiresAt:   expiresAt,
+		})
+		require.NoError(t, err)
+		require.True(t, url.Active)
+		return url
+	}
+	ended := create(&expired)
+	trashed := create(&expired)
+	running := create(&future)
+	deleteUrl(t, trashed.Id, user.Id)
+
+	deactivated := func() []int64 {
+		urls, err := strg.Url().DeactivateEnded()
+		require.NoError(t, err)
+		var ids []int64
+		for _, u := range urls {
+			if u.UserId == user.Id {
+				ids = append(ids, u.Id)
+			}
+		}
+		return ids
+	}
+
+	require.Equal(t, []int64{ended.Id}, deactivated())
+	// urls deactivated once are not returned again
+	require.Empty(t, deactivated())
+
+	// urls in the trash are left alone until they are restored
+	_, err := strg.Url().Restore(trashed.Id, user.Id, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []int64{trashed.Id}, deactivated())
+
+	url, err := strg.Url().GetByID(running.Id)
+	require.NoError(t, err)
+	require.True(t, url.Active)
+	deleteUser(t, user.Id)
+}
+
+func TestPurgeUrls(t *testing.T) {
+	purged := createUrl(t)
+	restored, err := strg.Url().Create(&repo.Url{
+		UserId:      purged.UserId,
+		OriginalUrl: faker.URL(),
+		HashedUrl:   utils.RandomString(10),
+	})
+	require.NoError(t, err)
+	deleteUrl(t, purged.Id, purged.UserId)
+	deleteUrl(t, restored.Id, restored.UserId)
+	_, err = strg.Url().Restore(restored.Id, restored.UserId, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	trash := func() []*repo.Url {
+		result, err := strg.Url().GetAll(&repo.GetAllUrlsParams{
+			Limit:   10,
+			Page:    1,
+			UserID:  purged.UserId,
+			Deleted: true,
+		})
+		require.NoError(t, err)
+		return result.Urls
+	}
+
+	// urls deleted after the cut-off stay in the trash
+	_, err = strg.Url().Purge(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, trash(), 1)
+
+	count, err := strg.Url().Purge(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+	require.Empty(t, trash())
+	_, err = strg.Url().GetByID(purged.Id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// restored urls are not in the trash anymore
+	url, err := strg.Url().GetByID(restored.Id)
+	require.NoError(t, err)
+	require.Nil(t, url.DeletedAt)
+
+	// purging again leaves the rest alone
+	_, err = strg.Url().Purge(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	_, err = strg.Url().GetByID(restored.Id)
+	require.NoError(t, err)
+	deleteUser(t, purged.UserId)
+}
+
+func TestConsumeClick(t *testing.T) {
+	url := createUrl(t)
+	remaining, err := strg.Url().ConsumeClick(url.Id)
+	require.NoError(t, err)
+	require.Equal(t, *url.MaxClicks-1, remaining)
+
+	_, err = strg.Url().ConsumeClick(-1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, url.UserId)
+}
+
+func TestConsumeClickConcurrently(t *testing.T) {
+	const maxClicks = 20
+
+	url := createUrl(t)
+	click := int64(maxClicks)
+	_, err := strg.Url().Update(&repo.Url{
+		Id:        url.Id,
+		UserId:    url.UserId,
+		HashedUrl: url.HashedUrl,
+		MaxClicks: &click,
+	}, url.UserId)
+	require.NoError(t, err)
+
+	var (
+		wg              sync.WaitGroup
+		allowed, failed int64
+	)
+	for i := 0; i < maxClicks*3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := strg.Url().ConsumeClick(url.Id)
+			if err == nil {
+				atomic.AddInt64(&allowed, 1)
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				atomic.AddInt64(&failed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Zero(t, failed)
+	require.Equal(t, int64(maxClicks), allowed)
+	url2, err := strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), *url2.MaxClicks)
 	deleteUser(t, url.UserId)
 }
 
@@ -109,6 +529,27 @@ func TestGetAllUrl(t *testing.T) {
 	})
 	require.Empty(t, urls2.Urls)
 	require.Equal(t, urls2.Count, int32(0))
+
+	urls3, err := strg.Url().GetAll(&repo.GetAllUrlsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: userId[0],
+		Status: repo.UrlStatusActive,
+		SortBy: "clicks",
+		Search: "' OR 1=1 --",
+	})
+	require.NoError(t, err)
+	require.Empty(t, urls3.Urls)
+
+	urls4, err := strg.Url().GetAll(&repo.GetAllUrlsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: userId[0],
+		Status: repo.UrlStatusActive,
+	})
+	require.NoError(t, err)
+	require.Len(t, urls4.Urls, 1)
+	require.Equal(t, int32(1), urls4.Count)
 	for i := 0; i < 10; i++ {
 		deleteUser(t, userId[i])
 	}
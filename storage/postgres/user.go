@@ -3,6 +3,7 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/SaidovZohid/competition-project/pkg/utils"
 	"github.com/SaidovZohid/competition-project/storage/repo"
@@ -58,7 +59,7 @@ func (ur *userRepo) Get(id int64) (*repo.User, error) {
 			email,
 			created_at
 		FROM users
-		WHERE id=$1
+		WHERE id=$1 AND deleted_at IS NULL
 	`
 	var (
 		firstName, lastName sql.NullString
@@ -89,13 +90,11 @@ func (ur *userRepo) GetAll(params *repo.GetAllUsersParams) (*repo.GetAllUsersRes
 
 	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
 
-	filter := ""
+	var args []interface{}
+	filter := " WHERE deleted_at IS NULL "
 	if params.Search != "" {
-		str := "%" + params.Search + "%"
-		filter += fmt.Sprintf(`
-		WHERE first_name ILIKE '%s' OR last_name ILIKE '%s' OR email ILIKE '%s'	`,
-			str, str, str,
-		)
+		args = append(args, "%"+params.Search+"%")
+		filter += " AND (first_name ILIKE $1 OR last_name ILIKE $1 OR email ILIKE $1) "
 	}
 
 	query := `
@@ -110,7 +109,7 @@ func (ur *userRepo) GetAll(params *repo.GetAllUsersParams) (*repo.GetAllUsersRes
 		ORDER BY created_at desc
 		` + limit
 
-	rows, err := ur.db.Query(query)
+	rows, err := ur.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +137,7 @@ func (ur *userRepo) GetAll(params *repo.GetAllUsersParams) (*repo.GetAllUsersRes
 	}
 
 	queryCount := `SELECT count(1) FROM users ` + filter
-	err = ur.db.QueryRow(queryCount).Scan(&result.Count)
+	err = ur.db.QueryRow(queryCount, args...).Scan(&result.Count)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +157,7 @@ func (ur *userRepo) GetByEmail(email string) (*repo.User, error) {
 			password,
 			created_at
 		from users
-		where email=$1
+		where email=$1 and deleted_at IS NULL
 	`
 
 	var (
@@ -189,7 +188,7 @@ func (ur *userRepo) Update(user *repo.User) (*repo.User, error) {
 		UPDATE users SET
 			first_name=$1,
 			last_name=$2
-		WHERE id=$3
+		WHERE id=$3 AND deleted_at IS NULL
 		RETURNING id, email, created_at
 	`
 
@@ -210,18 +209,128 @@ func (ur *userRepo) Update(user *repo.User) (*repo.User, error) {
 	return &result, nil
 }
 
-func (ur *userRepo) Delete(id int64) error {
-	query := ` DELETE FROM users WHERE id=$1 `
+func (ur *userRepo) UpdatePassword(id int64, password string) error {
+	query := `UPDATE users SET password=$1 WHERE id=$2 AND deleted_at IS NULL`
 
-	res, err := ur.db.Exec(
-		query,
-		id,
-	)
+	res, err := ur.db.Exec(query, password, id)
 	if err != nil {
 		return err
 	}
 	if count, _ := res.RowsAffected(); count == 0 {
 		return sql.ErrNoRows
 	}
+
 	return nil
 }
+
+func (ur *userRepo) Delete(id int64) error {
+	tx, err := ur.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	query := ` UPDATE users SET deleted_at=now() WHERE id=$1 AND deleted_at IS NULL RETURNING deleted_at `
+	err = tx.QueryRow(query, id).Scan(&deletedAt)
+	if err != nil {
+		return err
+	}
+
+	// links of the user go to the trash with the same time, so restoring
+	// the user brings back exactly these links
+	_, err = tx.Exec(` UPDATE urls SET deleted_at=$1 WHERE user_id=$2 AND deleted_at IS NULL `, deletedAt, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ur *userRepo) GetDeletedByEmail(email string, since time.Time) (*repo.User, error) {
+	var result repo.User
+
+	query := `
+		select
+			id,
+			first_name,
+			last_name,
+			email,
+			password,
+			created_at
+		from users
+		where email=$1 and deleted_at >= $2
+		order by deleted_at desc
+		limit 1
+	`
+
+	var (
+		firstName, lastName sql.NullString
+	)
+	err := ur.db.QueryRow(query, email, since).Scan(
+		&result.Id,
+		&firstName,
+		&lastName,
+		&result.Email,
+		&result.Password,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result.FirstName = firstName.String
+	result.LastName = lastName.String
+
+	return &result, nil
+}
+
+func (ur *userRepo) Restore(id int64, since time.Time) error {
+	tx, err := ur.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the email may be taken by a new account in the meantime
+	var taken bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users o, users u
+			WHERE u.id=$1 AND o.email=u.email AND o.deleted_at IS NULL
+		)
+	`, id).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return repo.ErrAlreadyExists
+	}
+
+	var deletedAt time.Time
+	query := ` SELECT deleted_at FROM users WHERE id=$1 AND deleted_at >= $2 FOR UPDATE `
+	err = tx.QueryRow(query, id, since).Scan(&deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(` UPDATE users SET deleted_at=NULL WHERE id=$1 `, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(` UPDATE urls SET deleted_at=NULL WHERE user_id=$1 AND deleted_at=$2 `, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ur *userRepo) Purge(before time.Time) (int64, error) {
+	res, err := ur.db.Exec(` DELETE FROM users WHERE deleted_at < $1 `, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
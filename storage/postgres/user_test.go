@@ -3,6 +3,7 @@ package postgres_test
 import (
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/SaidovZohid/competition-project/pkg/utils"
 	"github.com/SaidovZohid/competition-project/storage/repo"
@@ -57,6 +58,36 @@ func TestDeleteUser(t *testing.T) {
 	require.Error(t, err, sql.ErrNoRows)
 }
 
+func TestRestoreUser(t *testing.T) {
+	url := createUrl(t)
+	user, err := strg.User().Get(url.UserId)
+	require.NoError(t, err)
+	deleteUser(t, user.Id)
+
+	_, err = strg.User().Get(user.Id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = strg.Url().GetByID(url.Id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	user2, err := strg.User().GetDeletedByEmail(user.Email, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, user.Id, user2.Id)
+
+	err = strg.User().Restore(user.Id, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	_, err = strg.User().Get(user.Id)
+	require.NoError(t, err)
+	_, err = strg.Url().GetByID(url.Id)
+	require.NoError(t, err)
+
+	deleteUser(t, user.Id)
+	_, err = strg.User().Purge(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	err = strg.User().Restore(user.Id, time.Now().Add(-time.Hour))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestGetAllUsers(t *testing.T) {
 	ids := make([]int64, 0)
 	for i := 0; i < 10; i++ {
@@ -102,3 +133,17 @@ func TestUpdateUser(t *testing.T) {
 	require.Error(t, err, sql.ErrNoRows)
 	require.Nil(t, user3)
 }
+
+func TestUpdateUserPassword(t *testing.T) {
+	user := createUser(t)
+	err := strg.User().UpdatePassword(user.Id, "new-password-hash")
+	require.NoError(t, err)
+
+	user2, err := strg.User().GetByEmail(user.Email)
+	require.NoError(t, err)
+	require.Equal(t, "new-password-hash", user2.Password)
+	deleteUser(t, user.Id)
+
+	err = strg.User().UpdatePassword(user.Id, "other-password-hash")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
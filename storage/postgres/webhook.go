@@ -0,0 +1,402 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/utils"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type webhookRepo struct {
+	db *sqlx.DB
+}
+
+func NewWebhook(db *sqlx.DB) repo.WebhookStorageI {
+	return &webhookRepo{
+		db: db,
+	}
+}
+
+const deliveryColumns = `
+	d.id,
+	d.webhook_id,
+	d.event,
+	d.payload,
+	d.status,
+	d.attempts,
+	d.next_attempt_at,
+	d.status_code,
+	d.error,
+	d.last_attempt_at,
+	d.delivered_at,
+	d.created_at
+`
+
+// scanDelivery scans a row selected with deliveryColumns, extra
+// destinations are scanned after the delivery columns
+func scanDelivery(row rowScanner, extra ...interface{}) (*repo.WebhookDelivery, error) {
+	var (
+		result    repo.WebhookDelivery
+		payload   string
+		errorText sql.NullString
+	)
+
+	dest := []interface{}{
+		&result.Id,
+		&result.WebhookId,
+		&result.Event,
+		&payload,
+		&result.Status,
+		&result.Attempts,
+		&result.NextAttemptAt,
+		&result.StatusCode,
+		&errorText,
+		&result.LastAttemptAt,
+		&result.DeliveredAt,
+		&result.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	result.Payload = []byte(payload)
+	result.Error = errorText.String
+
+	return &result, nil
+}
+
+func (wr *webhookRepo) Create(webhook *repo.Webhook) (*repo.Webhook, error) {
+	query := `
+		insert into webhooks(
+			user_id,
+			url,
+			secret,
+			events,
+			is_active
+		) values ($1, $2, $3, $4, $5)
+		returning id, created_at
+	`
+
+	err := wr.db.QueryRow(
+		query,
+		webhook.UserId,
+		webhook.Url,
+		webhook.Secret,
+		pq.Array(webhook.Events),
+		webhook.Active,
+	).Scan(
+		&webhook.Id,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (wr *webhookRepo) Get(id int64) (*repo.Webhook, error) {
+	var result repo.Webhook
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			url,
+			secret,
+			events,
+			is_active,
+			created_at
+		FROM webhooks
+		WHERE id=$1
+	`
+
+	err := wr.db.QueryRow(query, id).Scan(
+		&result.Id,
+		&result.UserId,
+		&result.Url,
+		&result.Secret,
+		pq.Array(&result.Events),
+		&result.Active,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (wr *webhookRepo) GetAll(userID int64) ([]*repo.Webhook, error) {
+	result := make([]*repo.Webhook, 0)
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			url,
+			secret,
+			events,
+			is_active,
+			created_at
+		FROM webhooks
+		WHERE user_id=$1
+		ORDER BY id
+	`
+	rows, err := wr.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w repo.Webhook
+		err := rows.Scan(
+			&w.Id,
+			&w.UserId,
+			&w.Url,
+			&w.Secret,
+			pq.Array(&w.Events),
+			&w.Active,
+			&w.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &w)
+	}
+
+	return result, rows.Err()
+}
+
+func (wr *webhookRepo) Update(webhook *repo.Webhook) (*repo.Webhook, error) {
+	query := `
+		update webhooks set
+			url=$1,
+			events=$2,
+			is_active=$3
+		where id=$4 and user_id=$5
+		returning secret, created_at
+	`
+
+	err := wr.db.QueryRow(
+		query,
+		webhook.Url,
+		pq.Array(webhook.Events),
+		webhook.Active,
+		webhook.Id,
+		webhook.UserId,
+	).Scan(
+		&webhook.Secret,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (wr *webhookRepo) Delete(id, userID int64) error {
+	query := ` delete from webhooks where id=$1 and user_id=$2 `
+
+	res, err := wr.db.Exec(
+		query,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (wr *webhookRepo) Enqueue(e *repo.WebhookEvent) (int64, error) {
+	query := `
+		insert into webhook_deliveries(
+			webhook_id,
+			event,
+			event_key,
+			payload
+		)
+		SELECT id, $2, $3::varchar, $4::text
+		FROM webhooks
+		WHERE user_id=$1 AND is_active AND $2 = ANY(events) AND NOT EXISTS (
+			SELECT 1 FROM webhook_deliveries d
+			WHERE d.webhook_id = webhooks.id AND d.event_key = $3
+		)
+		on conflict (webhook_id, event_key) do nothing
+	`
+
+	res, err := wr.db.Exec(
+		query,
+		e.UserId,
+		e.Event,
+		utils.NullString(e.Key),
+		string(e.Payload),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (wr *webhookRepo) Subscribed(userID int64, event string) (bool, error) {
+	var subscribed bool
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM webhooks
+			WHERE user_id=$1 AND is_active AND $2 = ANY(events)
+		)
+	`
+	err := wr.db.QueryRow(query, userID, event).Scan(&subscribed)
+	if err != nil {
+		return false, err
+	}
+
+	return subscribed, nil
+}
+
+func (wr *webhookRepo) ClaimDue(limit int, lease time.Duration) ([]*repo.WebhookDelivery, error) {
+	result := make([]*repo.WebhookDelivery, 0)
+
+	// deliveries of disabled webhooks wait until the webhook is enabled
+	query := `
+		UPDATE webhook_deliveries d SET next_attempt_at = now() + $2::float8 * interval '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT dd.id FROM webhook_deliveries dd
+			JOIN webhooks ww ON ww.id = dd.webhook_id
+			WHERE dd.status=$3 AND dd.next_attempt_at <= now() AND ww.is_active
+			ORDER BY dd.next_attempt_at, dd.id
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns + `, w.url, w.secret
+	`
+	rows, err := wr.db.Query(query, limit, lease.Seconds(), repo.WebhookDeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url, secret string
+		d, err := scanDelivery(rows, &url, &secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Url = url
+		d.Secret = secret
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+func (wr *webhookRepo) SaveAttempt(d *repo.WebhookDelivery) error {
+	query := `
+		update webhook_deliveries set
+			status=$1,
+			attempts=$2,
+			next_attempt_at=$3,
+			status_code=$4,
+			error=$5,
+			last_attempt_at=now(),
+			delivered_at=CASE WHEN $1::varchar = $6::varchar THEN now() END
+		where id=$7
+		returning last_attempt_at, delivered_at
+	`
+
+	err := wr.db.QueryRow(
+		query,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.StatusCode,
+		utils.NullString(d.Error),
+		repo.WebhookDeliveryDelivered,
+		d.Id,
+	).Scan(
+		&d.LastAttemptAt,
+		&d.DeliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wr *webhookRepo) GetDelivery(id int64) (*repo.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		WHERE d.id=$1
+	`
+
+	return scanDelivery(wr.db.QueryRow(query, id))
+}
+
+func (wr *webhookRepo) GetDeliveries(params *repo.GetWebhookDeliveriesParams) (*repo.GetWebhookDeliveriesResult, error) {
+	result := repo.GetWebhookDeliveriesResult{
+		Deliveries: make([]*repo.WebhookDelivery, 0),
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" limit %d offset %d ", params.Limit, offset)
+
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		WHERE d.webhook_id=$1
+		ORDER BY d.created_at DESC, d.id DESC
+		` + limit
+	rows, err := wr.db.Query(query, params.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Deliveries = append(result.Deliveries, d)
+	}
+
+	queryCount := ` SELECT count(1) FROM webhook_deliveries WHERE webhook_id=$1 `
+	err = wr.db.QueryRow(queryCount, params.WebhookID).Scan(&result.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (wr *webhookRepo) Redeliver(id int64) (*repo.WebhookDelivery, error) {
+	query := `
+		insert into webhook_deliveries as d(
+			webhook_id,
+			event,
+			payload
+		)
+		SELECT webhook_id, event, payload
+		FROM webhook_deliveries
+		WHERE id=$1
+		returning ` + deliveryColumns
+
+	return scanDelivery(wr.db.QueryRow(query, id))
+}
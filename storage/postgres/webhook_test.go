@@ -0,0 +1,134 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func createWebhook(t *testing.T, userID int64, events ...string) *repo.Webhook {
+	webhook, err := strg.Webhook().Create(&repo.Webhook{
+		UserId: userID,
+		Url:    faker.URL(),
+		Secret: faker.Password(),
+		Events: events,
+		Active: true,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, webhook.Id)
+	require.NotZero(t, webhook.CreatedAt)
+
+	return webhook
+}
+
+func TestWebhook(t *testing.T) {
+	user := createUser(t)
+	webhook := createWebhook(t, user.Id, "url.created")
+
+	webhook.Events = []string{"url.created", "url.deleted"}
+	webhook.Active = false
+	_, err := strg.Webhook().Update(webhook)
+	require.NoError(t, err)
+
+	webhooks, err := strg.Webhook().GetAll(user.Id)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.Equal(t, webhook.Events, webhooks[0].Events)
+	require.Equal(t, webhook.Secret, webhooks[0].Secret)
+	require.False(t, webhooks[0].Active)
+
+	err = strg.Webhook().Delete(webhook.Id, user.Id+1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = strg.Webhook().Delete(webhook.Id, user.Id)
+	require.NoError(t, err)
+	_, err = strg.Webhook().Get(webhook.Id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	deleteUser(t, user.Id)
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	user := createUser(t)
+	webhook := createWebhook(t, user.Id, "url.created", "url.clicks")
+	createWebhook(t, user.Id, "url.deleted")
+
+	subscribed, err := strg.Webhook().Subscribed(user.Id, "url.clicks")
+	require.NoError(t, err)
+	require.True(t, subscribed)
+	subscribed, err = strg.Webhook().Subscribed(user.Id, "url.expired")
+	require.NoError(t, err)
+	require.False(t, subscribed)
+
+	count, err := strg.Webhook().Enqueue(&repo.WebhookEvent{
+		UserId:  user.Id,
+		Event:   "url.created",
+		Payload: []byte(`{"event":"url.created"}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	// events with a key are queued once
+	for i := 0; i < 2; i++ {
+		_, err = strg.Webhook().Enqueue(&repo.WebhookEvent{
+			UserId:  user.Id,
+			Event:   "url.clicks",
+			Key:     "url.clicks:1:100",
+			Payload: []byte(`{"event":"url.clicks"}`),
+		})
+		require.NoError(t, err)
+	}
+
+	result, err := strg.Webhook().GetDeliveries(&repo.GetWebhookDeliveriesParams{
+		Limit:     10,
+		Page:      1,
+		WebhookID: webhook.Id,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Count)
+	require.Len(t, result.Deliveries, 2)
+
+	var claimed []*repo.WebhookDelivery
+	due, err := strg.Webhook().ClaimDue(1000, time.Minute)
+	require.NoError(t, err)
+	for _, d := range due {
+		if d.WebhookId == webhook.Id {
+			claimed = append(claimed, d)
+		}
+	}
+	require.Len(t, claimed, 2)
+	require.Equal(t, webhook.Url, claimed[0].Url)
+	require.Equal(t, webhook.Secret, claimed[0].Secret)
+
+	// claimed deliveries are leased
+	due, err = strg.Webhook().ClaimDue(1000, time.Minute)
+	require.NoError(t, err)
+	for _, d := range due {
+		require.NotEqual(t, webhook.Id, d.WebhookId)
+	}
+
+	d := claimed[0]
+	d.Status = repo.WebhookDeliveryDelivered
+	d.Attempts = 1
+	d.NextAttemptAt = nil
+	d.StatusCode = 200
+	err = strg.Webhook().SaveAttempt(d)
+	require.NoError(t, err)
+	require.NotNil(t, d.DeliveredAt)
+
+	d2, err := strg.Webhook().GetDelivery(d.Id)
+	require.NoError(t, err)
+	require.Equal(t, repo.WebhookDeliveryDelivered, d2.Status)
+	require.Equal(t, 200, d2.StatusCode)
+	require.Equal(t, string(d.Payload), string(d2.Payload))
+
+	d3, err := strg.Webhook().Redeliver(d.Id)
+	require.NoError(t, err)
+	require.NotEqual(t, d.Id, d3.Id)
+	require.Equal(t, repo.WebhookDeliveryPending, d3.Status)
+	require.Zero(t, d3.Attempts)
+	require.Equal(t, string(d.Payload), string(d3.Payload))
+	deleteUser(t, user.Id)
+}
@@ -0,0 +1,27 @@
+package repo
+
+import "time"
+
+type ApiKeyStorageI interface {
+	Create(k *ApiKey) (*ApiKey, error)
+	// GetByHash returns the key with the hash, expired keys included
+	GetByHash(hash string) (*ApiKey, error)
+	GetAll(userID int64) ([]*ApiKey, error)
+	Delete(id, userID int64) error
+	// Touch marks the key as used now, it writes at most once a minute
+	Touch(id int64) error
+}
+
+type ApiKey struct {
+	Id     int64
+	UserId int64
+	Name   string
+	// Prefix is the visible start of the key, the key itself is never
+	// stored, only its hash
+	Prefix     string
+	Hash       string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
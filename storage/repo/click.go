@@ -0,0 +1,55 @@
+package repo
+
+import "time"
+
+type ClickStorageI interface {
+	Create(c *Click) (*Click, error)
+	GetStats(params *GetClickStatsParams) (*ClickStats, error)
+}
+
+type Click struct {
+	Id             int64
+	UrlId          int64
+	Referrer       string
+	UserAgent      string
+	IpHash         string
+	AcceptLanguage string
+	// DestinationId is the A/B variant served, 0 if the url has none
+	DestinationId int64
+	ClickedAt     time.Time
+	// UrlClicks is the click count of the url with this click, Create sets
+	// it from a counter so the clicks are not counted again
+	UrlClicks int64
+}
+
+type GetClickStatsParams struct {
+	UrlID    int64
+	Interval string
+	From     time.Time
+	To       time.Time
+}
+
+type ClickStats struct {
+	TotalClicks    int64
+	UniqueVisitors int64
+	Series         []*ClickSeriesPoint
+	Referrers      []*ClickCount
+	UserAgents     []*ClickCount
+	Destinations   []*DestinationClicks
+}
+
+type ClickSeriesPoint struct {
+	Bucket time.Time
+	Clicks int64
+}
+
+type ClickCount struct {
+	Value  string
+	Clicks int64
+}
+
+type DestinationClicks struct {
+	DestinationId  int64
+	Clicks         int64
+	UniqueVisitors int64
+}
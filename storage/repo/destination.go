@@ -0,0 +1,20 @@
+package repo
+
+import "time"
+
+type DestinationStorageI interface {
+	GetAll(urlID int64) ([]*Destination, error)
+	// Set replaces destinations of the url in one transaction. Rows with an
+	// Id are updated, rows without one are created and the others deleted.
+	Set(urlID int64, sticky bool, destinations []*Destination) ([]*Destination, error)
+}
+
+// Destination is one variant of an A/B split, visitors are spread
+// across the variants of a url in proportion to their weights
+type Destination struct {
+	Id        int64
+	UrlId     int64
+	TargetUrl string
+	Weight    int
+	CreatedAt time.Time
+}
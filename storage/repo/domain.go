@@ -0,0 +1,27 @@
+package repo
+
+import "time"
+
+type DomainStorageI interface {
+	Create(d *Domain) (*Domain, error)
+	Get(id int64) (*Domain, error)
+	// GetByHost returns only verified domains, unverified ones are just
+	// claims of the host
+	GetByHost(host string) (*Domain, error)
+	GetAll(userID int64) ([]*Domain, error)
+	// Verify marks the domain as verified, it returns ErrAlreadyExists when
+	// another domain of the host is verified already
+	Verify(id int64) (*Domain, error)
+	// Delete returns ErrInUse while links, also those in the trash, use
+	// the domain
+	Delete(id, userID int64) error
+}
+
+type Domain struct {
+	Id                int64
+	UserId            int64
+	Host              string
+	VerificationToken string
+	VerifiedAt        *time.Time
+	CreatedAt         time.Time
+}
@@ -0,0 +1,10 @@
+package repo
+
+import "errors"
+
+// ErrAlreadyExists is returned when a row violates one of the unique constraints
+var ErrAlreadyExists = errors.New("already exists")
+
+// ErrInUse is returned when a row can not be deleted since other rows
+// still refer to it
+var ErrInUse = errors.New("in use")
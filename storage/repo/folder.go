@@ -0,0 +1,22 @@
+package repo
+
+import "time"
+
+type FolderStorageI interface {
+	Create(f *Folder) (*Folder, error)
+	Get(id int64) (*Folder, error)
+	// GetAll returns folders of the user with their url counts
+	GetAll(userID int64) ([]*Folder, error)
+	Update(f *Folder) (*Folder, error)
+	// Delete removes the folder, its urls stay without a folder
+	Delete(id, userID int64) error
+}
+
+type Folder struct {
+	Id        int64
+	UserId    int64
+	Name      string
+	CreatedAt time.Time
+	// Urls is counted over urls which are not deleted, only GetAll fills it
+	Urls int64
+}
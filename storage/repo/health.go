@@ -0,0 +1,46 @@
+package repo
+
+import "time"
+
+type HealthStorageI interface {
+	// GetDue returns active urls which were not checked since the given
+	// time, urls which were never checked come first
+	GetDue(checkedBefore time.Time, limit int) ([]*Url, error)
+	// Save stores the result of a check, ConsecutiveFailures and
+	// BrokenSince are counted from the previous results
+	Save(h *UrlHealth) (*UrlHealth, error)
+	// GetBroken returns active urls of the user which failed at least
+	// Threshold checks in a row
+	GetBroken(params *GetBrokenUrlsParams) (*GetBrokenUrlsResult, error)
+}
+
+type UrlHealth struct {
+	UrlId      int64
+	StatusCode int
+	Latency    time.Duration
+	// FinalUrl is the address the redirects of the destination ended at
+	FinalUrl string
+	Error    string
+	// Broken tells whether this check failed
+	Broken              bool
+	ConsecutiveFailures int
+	BrokenSince         *time.Time
+	CheckedAt           time.Time
+}
+
+type BrokenUrl struct {
+	Url    *Url
+	Health *UrlHealth
+}
+
+type GetBrokenUrlsParams struct {
+	Limit     int32
+	Page      int32
+	UserID    int64
+	Threshold int
+}
+
+type GetBrokenUrlsResult struct {
+	Urls  []*BrokenUrl
+	Count int32
+}
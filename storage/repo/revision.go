@@ -0,0 +1,25 @@
+package repo
+
+import "time"
+
+type RevisionStorageI interface {
+	// GetAll returns revisions of the url, the newest first
+	GetAll(urlID int64) ([]*UrlRevision, error)
+	Get(urlID int64, revision int) (*UrlRevision, error)
+}
+
+// UrlRevision is the state of the url after a change. Revisions are
+// written by UrlStorageI.Update, the first change also writes the state
+// the url was created with as revision 1.
+type UrlRevision struct {
+	Id          int64
+	UrlId       int64
+	Revision    int
+	UserId      int64
+	OriginalUrl string
+	HashedUrl   string
+	MaxClicks   *int64
+	StartsAt    *time.Time
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,29 @@
+package repo
+
+import "time"
+
+type RuleStorageI interface {
+	// Create appends the rule to the end of the list when Position is 0
+	Create(r *Rule) (*Rule, error)
+	Get(id int64) (*Rule, error)
+	// GetAll returns rules of the url in the order they are evaluated
+	GetAll(urlID int64) ([]*Rule, error)
+	Update(r *Rule) (*Rule, error)
+	Delete(id, urlID int64) error
+}
+
+// Rule sends visitors matching all of its non empty conditions to TargetUrl
+type Rule struct {
+	Id       int64
+	UrlId    int64
+	Position int
+	OS       string
+	Device   string
+	Language string
+	// TimeFrom and TimeTo are minutes since midnight in Timezone
+	TimeFrom  *int
+	TimeTo    *int
+	Timezone  string
+	TargetUrl string
+	CreatedAt time.Time
+}
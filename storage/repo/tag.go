@@ -0,0 +1,26 @@
+package repo
+
+import "time"
+
+type TagStorageI interface {
+	Create(t *Tag) (*Tag, error)
+	Get(id int64) (*Tag, error)
+	// GetAll returns tags of the user with their url and click counts
+	GetAll(userID int64) ([]*Tag, error)
+	Update(t *Tag) (*Tag, error)
+	Delete(id, userID int64) error
+	// SetUrlTags replaces tags of the url, ids of tags which do not belong
+	// to the user are ignored
+	SetUrlTags(urlID, userID int64, tagIDs []int64) error
+}
+
+type Tag struct {
+	Id        int64
+	UserId    int64
+	Name      string
+	CreatedAt time.Time
+	// Urls and Clicks are counted over urls which are not deleted,
+	// only GetAll fills them
+	Urls   int64
+	Clicks int64
+}
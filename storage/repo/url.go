@@ -4,21 +4,91 @@ import "time"
 
 type UrlStorageI interface {
 	Create(u *Url) (*Url, error)
-	Get(url string) (*Url, error)
+	// CreateBulk creates the urls in one transaction, a failed row does not
+	// stop the others and its error is returned at the same index. Urls
+	// without a slug get one from newSlug, which is asked again after a
+	// collision.
+	CreateBulk(urls []*Url, newSlug SlugFunc) ([]error, error)
+	Get(slug string, domainID int64) (*Url, error)
+	GetByID(id int64) (*Url, error)
 	GetAll(params *GetAllUrlsParams) (*GetAllUrlsResult, error)
-	DecrementClick(url string) error
-	Update(u *Url) (*Url, error)
+	// Export calls fn for every url of the user which is not deleted,
+	// rows are read one by one so the urls are never all in memory
+	Export(userID int64, fn func(u *Url) error) error
+	// ConsumeClick atomically takes one click of a limited url and returns
+	// how many are left, sql.ErrNoRows means the limit is already reached
+	ConsumeClick(id int64) (int64, error)
+	// DeactivateEnded marks active urls that expired or ran out of clicks
+	// as inactive and returns them
+	DeactivateEnded() ([]*Url, error)
+	NextSlugSequence() (int64, error)
+	// Update changes the url of u.UserId, changes of the destination,
+	// slug, limits or schedule are kept as revisions made by actorID
+	Update(u *Url, actorID int64) (*Url, error)
+	// Delete moves the url to the trash, Get methods do not return it
+	// anymore, GetAll returns it only with Deleted set
 	Delete(id, userID int64) error
+	// Restore brings back the url if it was deleted after since,
+	// sql.ErrNoRows means it is not in the trash or deleted earlier
+	Restore(id, userID int64, since time.Time) (*Url, error)
+	// Purge removes urls deleted before the given time for good
+	Purge(before time.Time) (int64, error)
+	// GetMissingMetadata returns urls whose destination page was not
+	// fetched yet, newest first
+	GetMissingMetadata(limit int) ([]*Url, error)
+	// SetMetadata stores the page metadata of u, it is dropped when the
+	// destination of the url changed in the meantime
+	SetMetadata(u *Url) error
 }
 
+// SlugFunc returns a slug for a url of CreateBulk, attempt is the number
+// of collisions of the url so far. An error gives up on the url.
+type SlugFunc func(u *Url, attempt int) (string, error)
+
 type Url struct {
 	Id          int64
 	UserId      int64
 	OriginalUrl string
 	HashedUrl   string
 	MaxClicks   *int64
+	StartsAt    *time.Time
 	ExpiresAt   *time.Time
-	CreatedAt   time.Time
+	DomainId    int64
+	// Active is false once the url expired or ran out of clicks,
+	// InactiveReason tells which of them happened
+	Active         bool
+	InactiveReason string
+	DeactivatedAt  *time.Time
+	// StickyDestinations keeps visitors on the A/B variant they got first
+	StickyDestinations bool
+	DeletedAt          *time.Time
+	// FolderId is 0 when the url is not in a folder
+	FolderId int64
+	// FallbackUrl is where visitors go once the url expired or ran out
+	// of clicks, empty to show the default page
+	FallbackUrl string
+	// Preview fields are shown to crawlers of chat apps and social
+	// networks instead of the preview of the destination
+	PreviewTitle       string
+	PreviewDescription string
+	PreviewImage       string
+	// Meta fields are taken from the destination page in the background,
+	// MetaFetchedAt is nil until the page was fetched, even if the fetch
+	// failed
+	MetaTitle       string
+	MetaDescription string
+	CanonicalUrl    string
+	FaviconUrl      string
+	MetaFetchedAt   *time.Time
+	// Password is the bcrypt hash of the link password, empty if the
+	// link is not protected
+	Password  string
+	CreatedAt time.Time
+	// TagIds are ids of the url tags, ids of tags of other users are
+	// ignored. Update keeps the tags when TagIds is nil.
+	TagIds []int64
+	// Clicks is the number of recorded clicks, only GetAll fills it
+	Clicks int64
 }
 
 type GetAllUrlsResult struct {
@@ -26,9 +96,25 @@ type GetAllUrlsResult struct {
 	Count int32
 }
 
+const (
+	UrlStatusActive    = "active"
+	UrlStatusScheduled = "scheduled"
+	UrlStatusExpired   = "expired"
+	UrlStatusExhausted = "exhausted"
+)
+
 type GetAllUrlsParams struct {
-	Limit  int32
-	Page   int32
-	UserID int64
-	Search string
-}
\ No newline at end of file
+	Limit     int32
+	Page      int32
+	UserID    int64
+	Search    string
+	Status    string
+	SortBy    string
+	Ascending bool
+	From      *time.Time
+	To        *time.Time
+	TagID     int64
+	FolderID  int64
+	// Deleted lists urls in the trash instead of the active ones
+	Deleted bool
+}
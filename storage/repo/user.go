@@ -8,7 +8,18 @@ type UserStorageI interface {
 	GetByEmail(email string) (*User, error)
 	GetAll(params *GetAllUsersParams) (*GetAllUsersResult, error)
 	Update(u *User) (*User, error)
+	// UpdatePassword stores the new password hash of the user
+	UpdatePassword(id int64, password string) error
+	// Delete moves the user and the links of the user to the trash
 	Delete(userId int64) error
+	// GetDeletedByEmail returns the user deleted after since
+	GetDeletedByEmail(email string, since time.Time) (*User, error)
+	// Restore brings back the user deleted after since together with the
+	// links deleted with the user, repo.ErrAlreadyExists means the email
+	// is used by another account now
+	Restore(id int64, since time.Time) error
+	// Purge removes users deleted before the given time for good
+	Purge(before time.Time) (int64, error)
 }
 
 type User struct {
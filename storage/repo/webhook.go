@@ -0,0 +1,86 @@
+package repo
+
+import "time"
+
+type WebhookStorageI interface {
+	Create(w *Webhook) (*Webhook, error)
+	Get(id int64) (*Webhook, error)
+	GetAll(userID int64) ([]*Webhook, error)
+	// Update changes the url, events and active flag, the secret is kept
+	Update(w *Webhook) (*Webhook, error)
+	Delete(id, userID int64) error
+	// Enqueue queues a delivery of the event to every active webhook of
+	// the user subscribed to it and returns how many were queued. An event
+	// with a Key is queued at most once per webhook.
+	Enqueue(e *WebhookEvent) (int64, error)
+	// Subscribed reports whether the user has an active webhook which
+	// is subscribed to the event
+	Subscribed(userID int64, event string) (bool, error)
+	// ClaimDue returns pending deliveries which are due and moves their
+	// next attempt lease into the future, so they are not claimed twice
+	// while being sent
+	ClaimDue(limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	// SaveAttempt stores the result of a delivery attempt
+	SaveAttempt(d *WebhookDelivery) error
+	GetDelivery(id int64) (*WebhookDelivery, error)
+	// GetDeliveries returns deliveries of the webhook, newest first
+	GetDeliveries(params *GetWebhookDeliveriesParams) (*GetWebhookDeliveriesResult, error)
+	// Redeliver queues a copy of the delivery to be sent right away
+	Redeliver(id int64) (*WebhookDelivery, error)
+}
+
+type Webhook struct {
+	Id     int64
+	UserId int64
+	Url    string
+	// Secret signs the deliveries, it is never changed after creation
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedAt time.Time
+}
+
+type WebhookEvent struct {
+	UserId int64
+	Event  string
+	// Key makes the event unique per webhook, empty for events which may
+	// happen again
+	Key     string
+	Payload []byte
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookDelivery struct {
+	Id        int64
+	WebhookId int64
+	Event     string
+	Payload   []byte
+	Status    string
+	Attempts  int
+	// NextAttemptAt is nil once the delivery is delivered or failed
+	NextAttemptAt *time.Time
+	StatusCode    int
+	Error         string
+	LastAttemptAt *time.Time
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+	// Url and Secret are of the webhook, only ClaimDue fills them
+	Url    string
+	Secret string
+}
+
+type GetWebhookDeliveriesParams struct {
+	Limit     int32
+	Page      int32
+	WebhookID int64
+}
+
+type GetWebhookDeliveriesResult struct {
+	Deliveries []*WebhookDelivery
+	Count      int32
+}
@@ -9,17 +9,47 @@ import (
 type StorageI interface {
 	User() repo.UserStorageI
 	Url() repo.UrlStorageI
+	Click() repo.ClickStorageI
+	Domain() repo.DomainStorageI
+	Rule() repo.RuleStorageI
+	Destination() repo.DestinationStorageI
+	Revision() repo.RevisionStorageI
+	Tag() repo.TagStorageI
+	Folder() repo.FolderStorageI
+	Health() repo.HealthStorageI
+	Webhook() repo.WebhookStorageI
+	ApiKey() repo.ApiKeyStorageI
 }
 
 type storagePg struct {
-	userRepo repo.UserStorageI
-	urlRepo  repo.UrlStorageI
+	userRepo   repo.UserStorageI
+	urlRepo    repo.UrlStorageI
+	clickRepo  repo.ClickStorageI
+	domainRepo repo.DomainStorageI
+	ruleRepo   repo.RuleStorageI
+	destRepo   repo.DestinationStorageI
+	revRepo    repo.RevisionStorageI
+	tagRepo    repo.TagStorageI
+	folderRepo repo.FolderStorageI
+	healthRepo repo.HealthStorageI
+	hookRepo   repo.WebhookStorageI
+	keyRepo    repo.ApiKeyStorageI
 }
 
 func NewStoragePg(db *sqlx.DB) StorageI {
 	return &storagePg{
-		userRepo: postgres.NewUser(db),
-		urlRepo:  postgres.NewUrl(db),
+		userRepo:   postgres.NewUser(db),
+		urlRepo:    postgres.NewUrl(db),
+		clickRepo:  postgres.NewClick(db),
+		domainRepo: postgres.NewDomain(db),
+		ruleRepo:   postgres.NewRule(db),
+		destRepo:   postgres.NewDestination(db),
+		revRepo:    postgres.NewRevision(db),
+		tagRepo:    postgres.NewTag(db),
+		folderRepo: postgres.NewFolder(db),
+		healthRepo: postgres.NewHealth(db),
+		hookRepo:   postgres.NewWebhook(db),
+		keyRepo:    postgres.NewApiKey(db),
 	}
 }
 
@@ -30,3 +60,43 @@ func (s *storagePg) User() repo.UserStorageI {
 func (s *storagePg) Url() repo.UrlStorageI {
 	return s.urlRepo
 }
+
+func (s *storagePg) Click() repo.ClickStorageI {
+	return s.clickRepo
+}
+
+func (s *storagePg) Domain() repo.DomainStorageI {
+	return s.domainRepo
+}
+
+func (s *storagePg) Rule() repo.RuleStorageI {
+	return s.ruleRepo
+}
+
+func (s *storagePg) Destination() repo.DestinationStorageI {
+	return s.destRepo
+}
+
+func (s *storagePg) Revision() repo.RevisionStorageI {
+	return s.revRepo
+}
+
+func (s *storagePg) Tag() repo.TagStorageI {
+	return s.tagRepo
+}
+
+func (s *storagePg) Folder() repo.FolderStorageI {
+	return s.folderRepo
+}
+
+func (s *storagePg) Health() repo.HealthStorageI {
+	return s.healthRepo
+}
+
+func (s *storagePg) Webhook() repo.WebhookStorageI {
+	return s.hookRepo
+}
+
+func (s *storagePg) ApiKey() repo.ApiKeyStorageI {
+	return s.keyRepo
+}
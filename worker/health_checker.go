@@ -0,0 +1,237 @@
+package worker
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+)
+
+const (
+	// HealthCheckTick is how often the checker looks for links which are
+	// due, at most HealthCheckBatch of them are checked at once
+	HealthCheckTick  = time.Minute
+	HealthCheckBatch = 500
+
+	healthCheckUserAgent = "Mozilla/5.0 (compatible; LinkHealthChecker/1.0)"
+)
+
+// HealthChecker periodically requests destinations of active urls and
+// stores how they answered
+type HealthChecker struct {
+	storage     storage.StorageI
+	logger      *logger.Logger
+	client      *http.Client
+	interval    time.Duration
+	concurrency int
+	hostDelay   time.Duration
+}
+
+// NewHealthChecker connects to destinations through the dialer, since they
+// are given by users it should be netguard.NewDialer outside of tests
+func NewHealthChecker(strg storage.StorageI, log *logger.Logger, cfg config.HealthCheck, dialer *net.Dialer) *HealthChecker {
+	return &HealthChecker{
+		storage:     strg,
+		logger:      log,
+		client:      netguard.NewClient(cfg.Timeout, dialer),
+		interval:    cfg.Interval,
+		concurrency: cfg.Concurrency,
+		hostDelay:   cfg.HostDelay,
+	}
+}
+
+// Run checks due urls right away and then every HealthCheckTick until
+// ctx is done
+func (c *HealthChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(HealthCheckTick)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Check(ctx); err != nil {
+			c.logger.WithError(err).Error("failed to check url health")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check requests destinations of urls which were not checked for an
+// interval and saves the results
+func (c *HealthChecker) Check(ctx context.Context) error {
+	urls, err := c.storage.Health().GetDue(time.Now().Add(-c.interval), HealthCheckBatch)
+	if err != nil {
+		return err
+	}
+
+	var broken int
+	c.probeAll(ctx, urls, func(h *repo.UrlHealth) {
+		if h.Broken {
+			broken++
+		}
+		_, err := c.storage.Health().Save(h)
+		if err != nil {
+			c.logger.WithError(err).WithField("url_id", h.UrlId).Error("failed to save url health")
+		}
+	})
+
+	if len(urls) > 0 {
+		c.logger.WithField("urls", len(urls)).
+			WithField("failed", broken).
+			Info("url health checked")
+	}
+
+	return nil
+}
+
+// hostGate lets one request at a time reach a host and keeps hostDelay
+// between them
+type hostGate struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// probeAll probes the urls with at most concurrency requests in flight,
+// save is called for every result, one at a time
+func (c *HealthChecker) probeAll(ctx context.Context, urls []*repo.Url, save func(h *repo.UrlHealth)) {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		gates  = make(map[string]*hostGate)
+		queue  = make(chan *repo.Url)
+		saveMu sync.Mutex
+	)
+
+	gate := func(host string) *hostGate {
+		mu.Lock()
+		defer mu.Unlock()
+		g, ok := gates[host]
+		if !ok {
+			g = &hostGate{}
+			gates[host] = g
+		}
+		return g
+	}
+
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range queue {
+				var host string
+				if parsed, err := url.Parse(u.OriginalUrl); err == nil {
+					host = parsed.Host
+				}
+
+				g := gate(host)
+				g.mu.Lock()
+				if wait := time.Until(g.next); wait > 0 {
+					select {
+					case <-ctx.Done():
+					case <-time.After(wait):
+					}
+				}
+				h := c.Probe(ctx, u.OriginalUrl)
+				g.next = time.Now().Add(c.hostDelay)
+				g.mu.Unlock()
+
+				h.UrlId = u.Id
+				saveMu.Lock()
+				save(h)
+				saveMu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, u := range urls {
+		select {
+		case <-ctx.Done():
+			break loop
+		case queue <- u:
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// Probe requests the destination with HEAD, and with GET when HEAD fails,
+// since some servers do not answer HEAD requests properly. Redirects are
+// followed, anything but a 2xx or 3xx answer in the end is a failure.
+func (c *HealthChecker) Probe(ctx context.Context, rawUrl string) *repo.UrlHealth {
+	h := c.request(ctx, http.MethodHead, rawUrl)
+	if h.Broken && ctx.Err() == nil {
+		h = c.request(ctx, http.MethodGet, rawUrl)
+	}
+	return h
+}
+
+func (c *HealthChecker) request(ctx context.Context, method, rawUrl string) *repo.UrlHealth {
+	var h repo.UrlHealth
+
+	req, err := http.NewRequestWithContext(ctx, method, rawUrl, nil)
+	if err != nil {
+		h.Broken = true
+		h.Error = "invalid url"
+		return &h
+	}
+	req.Header.Set("User-Agent", healthCheckUserAgent)
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	h.Latency = time.Since(start)
+	if err != nil {
+		h.Broken = true
+		h.Error = requestError(err)
+		return &h
+	}
+	resp.Body.Close()
+
+	h.StatusCode = resp.StatusCode
+	h.FinalUrl = resp.Request.URL.String()
+	h.Broken = resp.StatusCode >= http.StatusBadRequest
+	if h.Broken {
+		h.Error = resp.Status
+	}
+
+	return &h
+}
+
+// requestError describes why a request failed without the details of the
+// error, which would tell users how hosts resolve and answer on our
+// network
+func requestError(err error) string {
+	var (
+		dnsErr     *net.DNSError
+		netErr     net.Error
+		hostErr    x509.HostnameError
+		authErr    x509.UnknownAuthorityError
+		invalidErr x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.Is(err, netguard.ErrForbiddenAddress):
+		return "destination address is not allowed"
+	case errors.As(err, &dnsErr):
+		return "host not found"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &hostErr), errors.As(err, &authErr), errors.As(err, &invalidErr):
+		return "invalid certificate"
+	default:
+		return "connection failed"
+	}
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChecker(concurrency int, hostDelay time.Duration) *HealthChecker {
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	return NewHealthChecker(nil, &log, config.HealthCheck{
+		Interval:    time.Hour,
+		Concurrency: concurrency,
+		HostDelay:   hostDelay,
+		Timeout:     time.Second,
+	}, &net.Dialer{})
+}
+
+func TestProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	checker := newTestChecker(1, time.Millisecond)
+
+	h := checker.Probe(context.Background(), server.URL+"/ok")
+	require.False(t, h.Broken)
+	require.Equal(t, http.StatusOK, h.StatusCode)
+	require.Equal(t, server.URL+"/ok", h.FinalUrl)
+
+	h = checker.Probe(context.Background(), server.URL+"/moved")
+	require.False(t, h.Broken)
+	require.Equal(t, server.URL+"/ok", h.FinalUrl)
+
+	h = checker.Probe(context.Background(), server.URL+"/get-only")
+	require.False(t, h.Broken)
+	require.Equal(t, http.StatusOK, h.StatusCode)
+
+	h = checker.Probe(context.Background(), server.URL+"/broken")
+	require.True(t, h.Broken)
+	require.Equal(t, http.StatusInternalServerError, h.StatusCode)
+	require.NotEmpty(t, h.Error)
+
+	closed := httptest.NewServer(mux)
+	closed.Close()
+	h = checker.Probe(context.Background(), closed.URL+"/ok")
+	require.True(t, h.Broken)
+	require.Zero(t, h.StatusCode)
+	require.Equal(t, "connection failed", h.Error)
+
+	h = checker.Probe(context.Background(), "http://host.invalid/")
+	require.True(t, h.Broken)
+	require.Equal(t, "host not found", h.Error)
+}
+
+func TestProbePrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	checker := NewHealthChecker(nil, &log, config.HealthCheck{
+		Concurrency: 1,
+		Timeout:     time.Second,
+	}, netguard.NewDialer())
+
+	// the test server listens on 127.0.0.1
+	h := checker.Probe(context.Background(), server.URL)
+	require.True(t, h.Broken)
+	require.Zero(t, h.StatusCode)
+	require.Empty(t, h.FinalUrl)
+	require.Equal(t, "destination address is not allowed", h.Error)
+	require.NotContains(t, h.Error, "127.0.0.1")
+}
+
+func TestProbeAllIsPolite(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	urls := make([]*repo.Url, 0)
+	for i := 1; i <= 5; i++ {
+		urls = append(urls, &repo.Url{Id: int64(i), OriginalUrl: server.URL})
+	}
+
+	var (
+		mu      sync.Mutex
+		results = make(map[int64]*repo.UrlHealth)
+	)
+	start := time.Now()
+	newTestChecker(3, 10*time.Millisecond).probeAll(context.Background(), urls, func(h *repo.UrlHealth) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[h.UrlId] = h
+	})
+
+	require.Len(t, results, 5)
+	for _, h := range results {
+		require.False(t, h.Broken)
+	}
+	// all urls share a host, so requests are sent one by one with the
+	// host delay between them
+	require.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+	require.GreaterOrEqual(t, time.Since(start), 4*10*time.Millisecond)
+}
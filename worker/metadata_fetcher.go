@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/pagemeta"
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+)
+
+const (
+	// MetadataTick is how often the fetcher looks for new urls when it is
+	// not woken up, at most MetadataBatch of them are fetched at once
+	MetadataTick  = time.Minute
+	MetadataBatch = 100
+)
+
+// MetadataFetcher fetches destination pages of new urls and stores their
+// title, description, canonical url and favicon
+type MetadataFetcher struct {
+	storage     storage.StorageI
+	logger      *logger.Logger
+	fetcher     *pagemeta.Fetcher
+	concurrency int
+	wake        chan struct{}
+}
+
+// NewMetadataFetcher connects to destinations through the dialer, see
+// pagemeta.NewFetcher
+func NewMetadataFetcher(strg storage.StorageI, log *logger.Logger, cfg config.Metadata, dialer *net.Dialer) *MetadataFetcher {
+	return &MetadataFetcher{
+		storage:     strg,
+		logger:      log,
+		fetcher:     pagemeta.NewFetcher(cfg.Timeout, cfg.MaxSize, dialer),
+		concurrency: cfg.Concurrency,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Wake makes Run fetch right away instead of waiting for the next tick,
+// it never blocks
+func (f *MetadataFetcher) Wake() {
+	if f == nil {
+		return
+	}
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run fetches right away and then every MetadataTick or when woken up,
+// until ctx is done
+func (f *MetadataFetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(MetadataTick)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Fetch(ctx); err != nil {
+			f.logger.WithError(err).Error("failed to fetch url metadata")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-f.wake:
+		}
+	}
+}
+
+// Fetch fetches pages of all urls without metadata, batch by batch. Urls
+// whose page could not be fetched get empty metadata, so they are not
+// tried again.
+func (f *MetadataFetcher) Fetch(ctx context.Context) error {
+	for ctx.Err() == nil {
+		urls, err := f.storage.Url().GetMissingMetadata(MetadataBatch)
+		if err != nil {
+			return err
+		}
+
+		var saveErr error
+		failed := f.fetchAll(ctx, urls, func(u *repo.Url) {
+			if err := f.storage.Url().SetMetadata(u); err != nil {
+				saveErr = err
+			}
+		})
+		if saveErr != nil {
+			return saveErr
+		}
+
+		if len(urls) > 0 {
+			f.logger.WithField("urls", len(urls)).
+				WithField("failed", failed).
+				Info("url metadata fetched")
+		}
+		if len(urls) < MetadataBatch {
+			break
+		}
+	}
+
+	return nil
+}
+
+// fetchAll fetches the pages with at most concurrency requests in flight
+// and fills in the metadata of the urls, save is called for every url, one
+// at a time. It returns how many pages could not be fetched.
+func (f *MetadataFetcher) fetchAll(ctx context.Context, urls []*repo.Url, save func(u *repo.Url)) int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+		queue  = make(chan *repo.Url)
+	)
+
+	for i := 0; i < f.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range queue {
+				m, err := f.fetcher.Fetch(ctx, u.OriginalUrl)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				if err != nil {
+					failed++
+					f.logger.WithError(err).WithField("url_id", u.Id).Debug("failed to fetch destination page")
+				} else {
+					u.MetaTitle = m.Title
+					u.MetaDescription = m.Description
+					u.CanonicalUrl = m.CanonicalUrl
+					u.FaviconUrl = m.FaviconUrl
+				}
+				save(u)
+				mu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, u := range urls {
+		select {
+		case <-ctx.Done():
+			break loop
+		case queue <- u:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return failed
+}
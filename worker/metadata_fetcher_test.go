@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchAllMetadata(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<head><title>Page</title><meta name="description" content="About"></head>`))
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	fetcher := NewMetadataFetcher(nil, &log, config.Metadata{
+		Concurrency: 2,
+		Timeout:     time.Second,
+		MaxSize:     1024,
+	}, &net.Dialer{})
+
+	urls := []*repo.Url{
+		{Id: 1, OriginalUrl: server.URL + "/page"},
+		{Id: 2, OriginalUrl: server.URL + "/file"},
+		{Id: 3, OriginalUrl: "http://127.0.0.1:0/unreachable"},
+	}
+
+	saved := make(map[int64]*repo.Url)
+	failed := fetcher.fetchAll(context.Background(), urls, func(u *repo.Url) {
+		saved[u.Id] = u
+	})
+	require.Equal(t, 2, failed)
+	require.Len(t, saved, 3)
+
+	require.Equal(t, "Page", saved[1].MetaTitle)
+	require.Equal(t, "About", saved[1].MetaDescription)
+	require.Equal(t, server.URL+"/favicon.ico", saved[1].FaviconUrl)
+
+	require.Empty(t, saved[2].MetaTitle)
+	require.Empty(t, saved[3].FaviconUrl)
+}
+
+func TestMetadataFetcherWake(t *testing.T) {
+	var nilFetcher *MetadataFetcher
+	nilFetcher.Wake()
+
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	fetcher := NewMetadataFetcher(nil, &log, config.Metadata{Concurrency: 1}, &net.Dialer{})
+
+	// waking up a busy fetcher many times never blocks the caller
+	for i := 0; i < 10; i++ {
+		fetcher.Wake()
+	}
+	require.Len(t, fetcher.wake, 1)
+}
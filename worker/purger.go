@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/storage"
+)
+
+const PurgeInterval = time.Hour
+
+// Purger removes urls and users which stayed in the trash longer than
+// the retention window
+type Purger struct {
+	storage   storage.StorageI
+	logger    *logger.Logger
+	retention time.Duration
+}
+
+func NewPurger(strg storage.StorageI, log *logger.Logger, retention time.Duration) *Purger {
+	return &Purger{
+		storage:   strg,
+		logger:    log,
+		retention: retention,
+	}
+}
+
+// Run purges once right away and then every PurgeInterval until ctx is done
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Purge(); err != nil {
+			p.logger.WithError(err).Error("failed to purge trash")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) Purge() error {
+	before := time.Now().Add(-p.retention)
+
+	urls, err := p.storage.Url().Purge(before)
+	if err != nil {
+		return err
+	}
+
+	users, err := p.storage.User().Purge(before)
+	if err != nil {
+		return err
+	}
+
+	if urls > 0 || users > 0 {
+		p.logger.WithField("urls", urls).
+			WithField("users", users).
+			Info("trash purged")
+	}
+
+	return nil
+}
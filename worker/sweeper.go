@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+)
+
+// Sweeper periodically deactivates urls which expired or ran out of clicks,
+// drops them from the redirect cache and tells the webhooks about them
+type Sweeper struct {
+	storage  storage.StorageI
+	inMemory storage.InMemoryStorageI
+	webhooks *WebhookSender
+	logger   *logger.Logger
+	interval time.Duration
+}
+
+func NewSweeper(strg storage.StorageI, inMemory storage.InMemoryStorageI, webhooks *WebhookSender, log *logger.Logger, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		storage:  strg,
+		inMemory: inMemory,
+		webhooks: webhooks,
+		logger:   log,
+		interval: interval,
+	}
+}
+
+// Run sweeps once right away and then every interval until ctx is done
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sweep(); err != nil {
+			s.logger.WithError(err).Error("failed to sweep urls")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sweeper) Sweep() error {
+	urls, err := s.storage.Url().DeactivateEnded()
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		keys = append(keys, storage.UrlCacheKey(url.DomainId, url.HashedUrl))
+		s.logger.WithField("url_id", url.Id).
+			WithField("reason", url.InactiveReason).
+			Info("url deactivated")
+
+		event := webhook.EventUrlExpired
+		if url.InactiveReason == repo.UrlStatusExhausted {
+			event = webhook.EventUrlExhausted
+		}
+		if err := s.webhooks.Notify(event, url); err != nil {
+			s.logger.WithError(err).WithField("url_id", url.Id).Error("failed to notify webhooks")
+		}
+	}
+
+	return s.inMemory.Del(keys...)
+}
@@ -0,0 +1,323 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/SaidovZohid/competition-project/pkg/slug"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
+	"github.com/SaidovZohid/competition-project/storage"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+)
+
+const (
+	// WebhookTick is how often the sender looks for due deliveries when
+	// it is not woken up, at most WebhookBatch of them are sent at once
+	WebhookTick  = 10 * time.Second
+	WebhookBatch = 100
+
+	webhookUserAgent = "Mozilla/5.0 (compatible; LinkWebhooks/1.0)"
+)
+
+// WebhookSender queues events of urls for the webhooks subscribed to them
+// and delivers them, failed deliveries are retried with a growing delay
+type WebhookSender struct {
+	storage       storage.StorageI
+	logger        *logger.Logger
+	client        *http.Client
+	concurrency   int
+	maxAttempts   int
+	publicBaseUrl string
+	wake          chan struct{}
+}
+
+// NewWebhookSender connects to endpoints through the dialer, since they are
+// given by users it should be netguard.NewDialer outside of tests
+func NewWebhookSender(strg storage.StorageI, log *logger.Logger, cfg config.Webhook, publicBaseUrl string, dialer *net.Dialer) *WebhookSender {
+	client := netguard.NewClient(cfg.Timeout, dialer)
+	// a redirect is an answer of its own, the body is not posted again
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &WebhookSender{
+		storage:       strg,
+		logger:        log,
+		client:        client,
+		concurrency:   cfg.Concurrency,
+		maxAttempts:   cfg.MaxAttempts,
+		publicBaseUrl: publicBaseUrl,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Notify queues the event of the url for the webhooks of its owner and
+// wakes up the sender when there is any. It is a no-op on a nil sender.
+func (s *WebhookSender) Notify(event string, u *repo.Url) error {
+	if s == nil {
+		return nil
+	}
+	return s.enqueue(event, u, "", 0)
+}
+
+// NotifyClicks queues webhook.EventUrlClicks when clicks, the click count
+// of the url after a click, is one of webhook.ClickThresholds. Counts grow
+// by one, so every threshold is sent once and other clicks do not reach
+// storage. Only Id and UserId of u are used, the url sent is read again so
+// cached urls can be passed.
+func (s *WebhookSender) NotifyClicks(u *repo.Url, clicks int64) error {
+	if s == nil || !webhook.IsClickThreshold(clicks) {
+		return nil
+	}
+	subscribed, err := s.storage.Webhook().Subscribed(u.UserId, webhook.EventUrlClicks)
+	if err != nil || !subscribed {
+		return err
+	}
+
+	// the url may have been deleted since the click
+	url, err := s.storage.Url().GetByID(u.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s:%d:%d", webhook.EventUrlClicks, url.Id, clicks)
+	return s.enqueue(webhook.EventUrlClicks, url, key, clicks)
+}
+
+func (s *WebhookSender) enqueue(event string, u *repo.Url, key string, clicks int64) error {
+	shortUrl, err := s.shortUrl(u)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(&webhook.Payload{
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data: &webhook.Data{
+			Url:    webhookUrl(u, shortUrl),
+			Clicks: clicks,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	count, err := s.storage.Webhook().Enqueue(&repo.WebhookEvent{
+		UserId:  u.UserId,
+		Event:   event,
+		Key:     key,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.Wake()
+	}
+
+	return nil
+}
+
+func (s *WebhookSender) shortUrl(u *repo.Url) (string, error) {
+	if u.DomainId == 0 {
+		return slug.Url(s.publicBaseUrl, "", u.HashedUrl), nil
+	}
+
+	domain, err := s.storage.Domain().Get(u.DomainId)
+	if err != nil {
+		return "", err
+	}
+
+	return slug.Url(s.publicBaseUrl, domain.Host, u.HashedUrl), nil
+}
+
+func webhookUrl(u *repo.Url, shortUrl string) *webhook.Url {
+	tagIds := u.TagIds
+	if tagIds == nil {
+		tagIds = make([]int64, 0)
+	}
+
+	return &webhook.Url{
+		Id:             u.Id,
+		UserId:         u.UserId,
+		OriginalUrl:    u.OriginalUrl,
+		HashedUrl:      u.HashedUrl,
+		ShortUrl:       shortUrl,
+		DomainId:       u.DomainId,
+		FolderId:       u.FolderId,
+		TagIds:         tagIds,
+		MaxClicks:      u.MaxClicks,
+		StartsAt:       u.StartsAt,
+		ExpiresAt:      u.ExpiresAt,
+		Active:         u.Active,
+		InactiveReason: u.InactiveReason,
+		CreatedAt:      u.CreatedAt,
+	}
+}
+
+// Wake makes Run send right away instead of waiting for the next tick,
+// it never blocks
+func (s *WebhookSender) Wake() {
+	if s == nil {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries right away and then every WebhookTick or when
+// woken up, until ctx is done
+func (s *WebhookSender) Run(ctx context.Context) {
+	ticker := time.NewTicker(WebhookTick)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Send(ctx); err != nil {
+			s.logger.WithError(err).Error("failed to send webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Send delivers all due deliveries, batch by batch
+func (s *WebhookSender) Send(ctx context.Context) error {
+	for ctx.Err() == nil {
+		deliveries, err := s.storage.Webhook().ClaimDue(WebhookBatch, s.lease())
+		if err != nil {
+			return err
+		}
+
+		var saveErr error
+		s.deliverAll(ctx, deliveries, func(d *repo.WebhookDelivery) {
+			if err := s.storage.Webhook().SaveAttempt(d); err != nil {
+				saveErr = err
+			}
+		})
+		if saveErr != nil {
+			return saveErr
+		}
+
+		if len(deliveries) < WebhookBatch {
+			break
+		}
+	}
+
+	return nil
+}
+
+// lease is long enough to send a whole batch even when every request
+// times out, claimed deliveries are not claimed again before it ends
+func (s *WebhookSender) lease() time.Duration {
+	return s.client.Timeout*time.Duration(WebhookBatch/s.concurrency+1) + time.Minute
+}
+
+// deliverAll delivers with at most concurrency requests in flight, save
+// is called for every attempt, one at a time
+func (s *WebhookSender) deliverAll(ctx context.Context, deliveries []*repo.WebhookDelivery, save func(d *repo.WebhookDelivery)) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		queue = make(chan *repo.WebhookDelivery)
+	)
+
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range queue {
+				s.Deliver(ctx, d)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				save(d)
+				mu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, d := range deliveries {
+		select {
+		case <-ctx.Done():
+			break loop
+		case queue <- d:
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// Deliver posts the payload to the webhook once and fills in the result
+// of the attempt. Anything but a 2xx answer is a failure, which is retried
+// after webhook.Backoff until maxAttempts is reached.
+func (s *WebhookSender) Deliver(ctx context.Context, d *repo.WebhookDelivery) {
+	d.Attempts++
+	d.StatusCode, d.Error = s.post(ctx, d)
+
+	if d.Error == "" {
+		d.Status = repo.WebhookDeliveryDelivered
+		d.NextAttemptAt = nil
+		return
+	}
+
+	if d.Attempts >= s.maxAttempts {
+		d.Status = repo.WebhookDeliveryFailed
+		d.NextAttemptAt = nil
+		return
+	}
+
+	next := time.Now().Add(webhook.Backoff(d.Attempts))
+	d.Status = repo.WebhookDeliveryPending
+	d.NextAttemptAt = &next
+}
+
+func (s *WebhookSender) post(ctx context.Context, d *repo.WebhookDelivery) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, "invalid url"
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(webhook.HeaderEvent, d.Event)
+	req.Header.Set(webhook.HeaderDelivery, strconv.FormatInt(d.Id, 10))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, requestError(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, resp.Status
+	}
+
+	return resp.StatusCode, ""
+}
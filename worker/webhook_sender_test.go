@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SaidovZohid/competition-project/config"
+	"github.com/SaidovZohid/competition-project/pkg/logger"
+	"github.com/SaidovZohid/competition-project/pkg/netguard"
+	"github.com/SaidovZohid/competition-project/pkg/webhook"
+	"github.com/SaidovZohid/competition-project/storage/repo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliverWebhook(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if r.Header.Get(webhook.HeaderEvent) != webhook.EventUrlCreated ||
+			!webhook.Verify("secret", timestamp, body, r.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	sender := NewWebhookSender(nil, &log, config.Webhook{
+		Concurrency: 1,
+		Timeout:     time.Second,
+		MaxAttempts: 2,
+	}, "http://localhost", &net.Dialer{})
+
+	newDelivery := func(secret string) *repo.WebhookDelivery {
+		return &repo.WebhookDelivery{
+			Id:      1,
+			Event:   webhook.EventUrlCreated,
+			Payload: []byte(`{"event":"url.created"}`),
+			Status:  repo.WebhookDeliveryPending,
+			Url:     server.URL,
+			Secret:  secret,
+		}
+	}
+
+	d := newDelivery("secret")
+	sender.Deliver(context.Background(), d)
+	require.Equal(t, repo.WebhookDeliveryDelivered, d.Status)
+	require.Equal(t, http.StatusOK, d.StatusCode)
+	require.Empty(t, d.Error)
+	require.Nil(t, d.NextAttemptAt)
+
+	// a wrong signature is answered with 401, which is retried
+	d = newDelivery("other")
+	sender.Deliver(context.Background(), d)
+	require.Equal(t, repo.WebhookDeliveryPending, d.Status)
+	require.Equal(t, http.StatusUnauthorized, d.StatusCode)
+	require.NotEmpty(t, d.Error)
+	require.NotNil(t, d.NextAttemptAt)
+	require.WithinDuration(t, time.Now().Add(webhook.Backoff(1)), *d.NextAttemptAt, time.Second)
+
+	status = http.StatusFound
+	d = newDelivery("secret")
+	d.Attempts = 1
+	sender.Deliver(context.Background(), d)
+	require.Equal(t, repo.WebhookDeliveryFailed, d.Status)
+	require.Equal(t, http.StatusFound, d.StatusCode)
+	require.Equal(t, 2, d.Attempts)
+	require.Nil(t, d.NextAttemptAt)
+}
+
+func TestDeliverWebhookPrivateAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	sender := NewWebhookSender(nil, &log, config.Webhook{
+		Concurrency: 1,
+		Timeout:     time.Second,
+		MaxAttempts: 2,
+	}, "http://localhost", netguard.NewDialer())
+
+	// the test server listens on 127.0.0.1
+	d := &repo.WebhookDelivery{
+		Id:      1,
+		Event:   webhook.EventUrlCreated,
+		Payload: []byte(`{"event":"url.created"}`),
+		Status:  repo.WebhookDeliveryPending,
+		Url:     server.URL,
+		Secret:  "secret",
+	}
+	sender.Deliver(context.Background(), d)
+	require.False(t, called)
+	require.Equal(t, repo.WebhookDeliveryPending, d.Status)
+	require.Zero(t, d.StatusCode)
+	require.Equal(t, "destination address is not allowed", d.Error)
+}
+
+func TestWebhookSenderNil(t *testing.T) {
+	var sender *WebhookSender
+	sender.Wake()
+	require.NoError(t, sender.Notify(webhook.EventUrlCreated, &repo.Url{Id: 1}))
+	require.NoError(t, sender.NotifyClicks(&repo.Url{Id: 1}, 10))
+}
+
+func TestNotifyClicksBetweenThresholds(t *testing.T) {
+	log := logger.Logger{Entry: logrus.NewEntry(logrus.New())}
+	// storage is nil, counts which are no threshold must not reach it
+	sender := NewWebhookSender(nil, &log, config.Webhook{Concurrency: 1}, "http://localhost", &net.Dialer{})
+
+	require.NoError(t, sender.NotifyClicks(&repo.Url{Id: 1}, 11))
+	require.NoError(t, sender.NotifyClicks(&repo.Url{Id: 1}, 5000000))
+}